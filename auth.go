@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/avast/stor-client/client"
+)
+
+// authentications of S3 (--s3-auth)
+const (
+	s3AuthNone  = "none"
+	s3AuthSigV4 = "sigv4"
+)
+
+// storAuth returns authenticator of requests to stor by headers, bearer token and basic auth (user),
+// nil means anonymous requests
+func storAuth(token, user, password string, headers map[string]string) storclient.Authenticator {
+	var auths []storclient.Authenticator
+	if len(headers) > 0 {
+		auths = append(auths, storclient.HeaderAuth(httpHeader(headers)))
+	}
+
+	if token != "" {
+		auths = append(auths, storclient.BearerAuth(token))
+	}
+
+	if user != "" {
+		auths = append(auths, storclient.BasicAuth(user, password))
+	}
+
+	if len(auths) == 0 {
+		return nil
+	}
+
+	return storclient.MultiAuth(auths...)
+}
+
+// s3Auth returns authenticator of requests to S3 by headers and SigV4 signing (nil credentials means without signing),
+// nil means anonymous requests
+func s3Auth(region string, credentials storclient.CredentialsProvider, headers map[string]string) storclient.Authenticator {
+	var auths []storclient.Authenticator
+	if len(headers) > 0 {
+		auths = append(auths, storclient.HeaderAuth(httpHeader(headers)))
+	}
+
+	// headers are signed too
+	if credentials != nil {
+		auths = append(auths, storclient.NewSigV4Signer(credentials, region))
+	}
+
+	if len(auths) == 0 {
+		return nil
+	}
+
+	return storclient.MultiAuth(auths...)
+}
+
+// s3Credentials returns static keys if they are set,
+// otherwise environment (AWS_ACCESS_KEY_ID, ...) and then profile of shared credentials file are used
+func s3Credentials(accessKey, secretKey, sessionToken, credentialsFile, profile string) storclient.CredentialsProvider {
+	if accessKey != "" || secretKey != "" {
+		return storclient.StaticCredentials(accessKey, secretKey, sessionToken)
+	}
+
+	return storclient.ChainCredentials(storclient.EnvCredentials(), storclient.SharedCredentials(credentialsFile, profile))
+}
+
+// flagS3Auth returns authenticator of requests to S3 by flags,
+// native S3 backend (--s3-bucket) sets headers and signs requests itself (see flagS3Config)
+func flagS3Auth() storclient.Authenticator {
+	if *s3Bucket != "" {
+		return nil
+	}
+
+	return s3Auth(*s3Region, flagS3Credentials(), *s3Headers)
+}
+
+// flagS3Credentials returns credentials of S3 by flags (nil for --s3-auth=none)
+func flagS3Credentials() storclient.CredentialsProvider {
+	if *s3AuthMode != s3AuthSigV4 {
+		return nil
+	}
+
+	return s3Credentials(*s3AccessKey, *s3SecretKey, *s3Token, *s3CredsFile, *s3Profile)
+}
+
+// s3Header returns custom headers of native S3 backend, nil without headers
+func s3Header(headers map[string]string) http.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	return httpHeader(headers)
+}
+
+func httpHeader(headers map[string]string) http.Header {
+	header := http.Header{}
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+
+	return header
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorAuth(t *testing.T) {
+	assert.Nil(t, storAuth("", "", "", nil))
+
+	req, err := http.NewRequest(http.MethodGet, "http://stor.domain.tld/sha", nil)
+	assert.NoError(t, err)
+
+	auth := storAuth("token", "", "", map[string]string{"x-api-key": "key"})
+	assert.NoError(t, auth.Authenticate(req))
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+	assert.Equal(t, "key", req.Header.Get("X-Api-Key"))
+
+	auth = storAuth("", "user", "password", nil)
+	assert.NoError(t, auth.Authenticate(req))
+	user, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "password", password)
+}
+
+func TestS3Auth(t *testing.T) {
+	assert.Nil(t, s3Auth("", nil, nil))
+
+	req, err := http.NewRequest(http.MethodGet, "https://bucket.s3.amazonaws.com/sha", nil)
+	assert.NoError(t, err)
+
+	auth := s3Auth("eu-west-1", s3Credentials("AKID", "SECRET", "", "", ""), map[string]string{"x-amz-request-payer": "requester"})
+	assert.NoError(t, auth.Authenticate(req))
+
+	authorization := req.Header.Get("Authorization")
+	assert.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/"), authorization)
+	assert.Contains(t, authorization, "/eu-west-1/s3/aws4_request")
+	assert.Contains(t, authorization, "x-amz-request-payer", "custom headers are signed")
+
+	assert.Nil(t, s3Header(nil))
+	assert.Equal(t, http.Header{"X-Amz-Expected-Bucket-Owner": []string{"123"}}, s3Header(map[string]string{"x-amz-expected-bucket-owner": "123"}))
+}
+
+func TestS3Credentials(t *testing.T) {
+	credentials, err := s3Credentials("AKID", "SECRET", "TOKEN", "", "").Retrieve()
+	assert.NoError(t, err)
+	assert.Equal(t, storclient.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET", SessionToken: "TOKEN"}, credentials)
+
+	_, err = s3Credentials("AKID", "", "", "", "").Retrieve()
+	assert.Error(t, err, "secret key is missing")
+}
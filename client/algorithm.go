@@ -0,0 +1,80 @@
+package storclient
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+
+	"github.com/avast/hashutil-go"
+	"lukechampine.com/blake3"
+)
+
+// Algorithm is hash algorithm of content address of objects
+type Algorithm string
+
+const (
+	// AlgorithmSHA256 - sha256 (stor)
+	AlgorithmSHA256 Algorithm = "sha256"
+	// AlgorithmSHA512 - sha512
+	AlgorithmSHA512 Algorithm = "sha512"
+	// AlgorithmBLAKE3 - blake3 with 256 bit output
+	AlgorithmBLAKE3 Algorithm = "blake3"
+	// AlgorithmSHA1 - sha1 (legacy stores)
+	AlgorithmSHA1 Algorithm = "sha1"
+)
+
+// DefaultAlgorithm is algorithm of stor
+const DefaultAlgorithm = AlgorithmSHA256
+
+// Algorithms are all supported algorithms
+var Algorithms = []Algorithm{AlgorithmSHA256, AlgorithmSHA512, AlgorithmBLAKE3, AlgorithmSHA1}
+
+// ParseAlgorithm returns algorithm of name, empty name means DefaultAlgorithm
+func ParseAlgorithm(name string) (Algorithm, error) {
+	if name == "" {
+		return DefaultAlgorithm, nil
+	}
+
+	for _, algorithm := range Algorithms {
+		if string(algorithm) == name {
+			return algorithm, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unknown hash algorithm %q", name)
+}
+
+// String returns name of algorithm, empty algorithm is DefaultAlgorithm
+func (algorithm Algorithm) String() string {
+	if algorithm == "" {
+		return string(DefaultAlgorithm)
+	}
+
+	return string(algorithm)
+}
+
+// New returns new hasher of algorithm
+func (algorithm Algorithm) New() hash.Hash {
+	switch algorithm {
+	case AlgorithmSHA512:
+		return sha512.New()
+	case AlgorithmBLAKE3:
+		return blake3.New(32, nil)
+	case AlgorithmSHA1:
+		return sha1.New()
+	default:
+		return sha256.New()
+	}
+}
+
+// Size returns size of hash in bytes
+func (algorithm Algorithm) Size() int {
+	return algorithm.New().Size()
+}
+
+// ParseHash returns hash of algorithm from hex string
+func (algorithm Algorithm) ParseHash(hexStr string) (hashutil.Hash, error) {
+	return hashutil.StringToHash(algorithm.New(), hexStr)
+}
@@ -0,0 +1,82 @@
+package storclient_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAlgorithm(t *testing.T) {
+	algorithm, err := storclient.ParseAlgorithm("")
+	assert.NoError(t, err)
+	assert.Equal(t, storclient.DefaultAlgorithm, algorithm)
+
+	for _, algorithm := range storclient.Algorithms {
+		parsed, err := storclient.ParseAlgorithm(string(algorithm))
+		assert.NoError(t, err)
+		assert.Equal(t, algorithm, parsed)
+	}
+
+	_, err = storclient.ParseAlgorithm("md4")
+	assert.Error(t, err)
+}
+
+func TestAlgorithmEmptyHash(t *testing.T) {
+	for algorithm, expected := range map[storclient.Algorithm]string{
+		storclient.AlgorithmSHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		storclient.AlgorithmSHA512: "cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e",
+		storclient.AlgorithmBLAKE3: "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262",
+		storclient.AlgorithmSHA1:   "da39a3ee5e6b4b0d3255bfef95601890afd80709",
+	} {
+		assert.Equal(t, expected, hashutil.EmptyHash(algorithm.New()).String(), algorithm.String())
+		assert.Equal(t, len(expected)/2, algorithm.Size(), algorithm.String())
+
+		hash, err := algorithm.ParseHash(expected)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, hash.String())
+	}
+}
+
+func TestGetWithAlgorithm(t *testing.T) {
+	content := []byte("sample")
+	hasher := storclient.AlgorithmSHA512.New()
+	_, _ = hasher.Write(content)
+	contentSha, err := hashutil.BytesToHash(storclient.AlgorithmSHA512.New(), hasher.Sum(nil))
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sha512/"+contentSha.String() {
+			_, _ = w.Write(content)
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "some_dir", storclient.StorClientOpts{
+		RetryAttempts: 2,
+		Algorithm:     storclient.AlgorithmSHA512,
+		StorTemplate:  "{{.Algorithm}}/{{.Sha}}",
+	})
+	assert.NoError(t, err)
+
+	reader, err := client.Get(context.Background(), contentSha)
+	assert.NoError(t, err)
+
+	got, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+	assert.NoError(t, reader.Close())
+
+	_, err = storclient.New(*serverURL, "some_dir", storclient.StorClientOpts{Algorithm: "md4"})
+	assert.Error(t, err, "unknown algorithm")
+}
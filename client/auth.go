@@ -0,0 +1,120 @@
+package storclient
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Authenticator authenticates requests to backend (see StorClientOpts.StorAuth and StorClientOpts.S3Auth),
+// e.g. BearerAuth, BasicAuth, HeaderAuth or SigV4Signer for private S3 buckets
+//
+// Authenticate is called for every request (every attempt of retry) and it modifies headers
+// (or url) of request, it must be safe for concurrent use
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthenticatorFunc is function used as Authenticator
+type AuthenticatorFunc func(req *http.Request) error
+
+// Authenticate calls f(req)
+func (f AuthenticatorFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+// BearerAuth returns Authenticator setting header "Authorization: Bearer <token>"
+func BearerAuth(token string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// BasicAuth returns Authenticator of HTTP basic authentication
+func BasicAuth(user, password string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.SetBasicAuth(user, password)
+		return nil
+	})
+}
+
+// HeaderAuth returns Authenticator setting custom headers (e.g. API key) to every request
+func HeaderAuth(headers http.Header) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		for name, values := range headers {
+			req.Header.Del(name)
+			for _, value := range values {
+				req.Header.Add(name, value)
+			}
+		}
+
+		return nil
+	})
+}
+
+// MultiAuth returns Authenticator applying all auths in order (nil auths are skipped),
+// e.g. custom headers before SigV4Signer, so headers are signed too
+func MultiAuth(auths ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		for _, auth := range auths {
+			if auth == nil {
+				continue
+			}
+
+			if err := auth.Authenticate(req); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+type backendKey struct{}
+
+// withBackend returns ctx of request to backend (s3, stor), authHTTPClient selects Authenticator by it
+func withBackend(ctx context.Context, backend string) context.Context {
+	return context.WithValue(ctx, backendKey{}, backend)
+}
+
+// requestBackend returns backend of request ctx (empty if it isn't set)
+func requestBackend(ctx context.Context) string {
+	backend, _ := ctx.Value(backendKey{}).(string)
+	return backend
+}
+
+// authHTTPClient authenticates requests by Authenticator of their backend,
+// requests without backend (or without Authenticator of backend) are anonymous
+type authHTTPClient struct {
+	httpClient httpClient
+	auths      map[string]Authenticator
+}
+
+// newAuthHTTPClient returns httpClient authenticating requests by auths of backends,
+// httpClient is returned if all auths are nil
+func newAuthHTTPClient(httpClient httpClient, auths map[string]Authenticator) httpClient {
+	for backend, auth := range auths {
+		if auth == nil {
+			delete(auths, backend)
+		}
+	}
+
+	if len(auths) == 0 {
+		return httpClient
+	}
+
+	return authHTTPClient{httpClient: httpClient, auths: auths}
+}
+
+func (c authHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	backend := requestBackend(req.Context())
+	if auth, ok := c.auths[backend]; ok {
+		if err := auth.Authenticate(req); err != nil {
+			return nil, errors.Wrapf(err, "Authentication of request to %s fail", backend)
+		}
+	}
+
+	return c.httpClient.Do(req)
+}
@@ -0,0 +1,163 @@
+package storclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticators(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "http://stor.domain.tld/sha", nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, BearerAuth("token").Authenticate(req))
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+
+	assert.NoError(t, BasicAuth("user", "password").Authenticate(req))
+	user, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "password", password)
+
+	req.Header.Set("X-Api-Key", "old")
+	auth := MultiAuth(nil, HeaderAuth(http.Header{"X-Api-Key": []string{"key"}, "X-Tenant": []string{"a", "b"}}), BearerAuth("token"))
+	assert.NoError(t, auth.Authenticate(req))
+	assert.Equal(t, []string{"key"}, req.Header["X-Api-Key"])
+	assert.Equal(t, []string{"a", "b"}, req.Header["X-Tenant"])
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+
+	failing := AuthenticatorFunc(func(req *http.Request) error { return errors.New("no token") })
+	assert.EqualError(t, MultiAuth(failing, BearerAuth("token")).Authenticate(req), "no token")
+}
+
+var authorizationRegexp = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/s3/aws4_request, SignedHeaders=([^,]+), Signature=([0-9a-f]{64})$`)
+
+// verifySigV4 verifies signature of request received by S3 stand-in (keys are map of access keys to secret keys)
+func verifySigV4(r *http.Request, keys map[string]string) error {
+	match := authorizationRegexp.FindStringSubmatch(r.Header.Get("Authorization"))
+	if match == nil {
+		return fmt.Errorf("invalid authorization %q", r.Header.Get("Authorization"))
+	}
+
+	secret, ok := keys[match[1]]
+	if !ok {
+		return fmt.Errorf("unknown access key %s", match[1])
+	}
+
+	t, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+
+	var headers strings.Builder
+	for _, name := range strings.Split(match[4], ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+
+	signer := &SigV4Signer{Region: match[3]}
+	canonical := canonicalRequest(r.Method, r.URL.Path, r.URL.Query(), headers.String(), match[4], r.Header.Get("X-Amz-Content-Sha256"))
+	if signature := signer.signature(Credentials{AccessKeyID: match[1], SecretAccessKey: secret}, t, canonical); signature != match[5] {
+		return fmt.Errorf("signature mismatch")
+	}
+
+	return nil
+}
+
+func TestClientAuth(t *testing.T) {
+	helloSha := testHash(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	missingSha := testHash(t, "01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b")
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := verifySigV4(r, map[string]string{"AKID": "SECRET"}); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		if !strings.HasSuffix(r.URL.Path, helloSha.String()) {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprint(w, "hello")
+	}))
+	defer s3.Close()
+
+	stor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("X-Amz-Date") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	defer stor.Close()
+
+	s3url, err := url.Parse(s3.URL + "/bucket")
+	assert.NoError(t, err)
+	storUrl, err := url.Parse(stor.URL)
+	assert.NoError(t, err)
+
+	newClient := func(credentials CredentialsProvider) *StorClient {
+		client, err := New(*storUrl, "", StorClientOpts{
+			S3URL:         s3url,
+			RetryAttempts: 2,
+			RetryDelay:    time.Millisecond,
+			S3Auth:        NewSigV4Signer(credentials, "eu-central-1"),
+			StorAuth:      BearerAuth("token"),
+		})
+		assert.NoError(t, err)
+
+		return client
+	}
+
+	get := func(client *StorClient, sha hashutil.Hash) (string, error) {
+		reader, err := client.Get(context.Background(), sha)
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+
+		content, err := ioutil.ReadAll(reader)
+		return string(content), err
+	}
+
+	client := newClient(StaticCredentials("AKID", "SECRET", ""))
+
+	content, err := get(client, helloSha)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", content)
+
+	// S3 returns 404 and authenticated stor returns 404 too
+	_, err = get(client, missingSha)
+	var statusErr *HTTPStatusError
+	if assert.True(t, errors.As(err, &statusErr)) {
+		assert.Equal(t, backendStor, statusErr.Backend)
+		assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	}
+
+	client = newClient(StaticCredentials("AKID", "WRONG", ""))
+	_, err = get(client, helloSha)
+	if assert.True(t, errors.As(err, &statusErr)) {
+		assert.Equal(t, backendS3, statusErr.Backend)
+		assert.Equal(t, http.StatusForbidden, statusErr.StatusCode)
+	}
+
+	client = newClient(StaticCredentials("", "", ""))
+	_, err = get(client, helloSha)
+	assert.Contains(t, err.Error(), "Authentication of request to s3 fail")
+}
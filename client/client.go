@@ -6,6 +6,7 @@ SYNOPSIS
 	client := storclient.New(storageUrl, storclient.StorClientOpts{})
 
 	client.Start()
+	defer client.Close()
 
 	for _, sha := range shaList {
 		client.Download(sha)
@@ -13,19 +14,43 @@ SYNOPSIS
 
 	downloadStatus := client.Wait()
 
+client can be reused for more batches of downloads, batches share worker pool and
+http transport of client and can be processed concurrently
+
+	batch := client.NewBatch()
+
+	for _, sha := range shaList {
+		batch.Download(sha)
+	}
+
+	batchStatus := batch.Wait()
+
+single object can be streamed (e.g. to memory or parser) without touching disk,
+hash (sha256 by default, see Algorithm) is verified at EOF
+
+	reader, err := client.Get(ctx, sha)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
 */
 package storclient
 
 import (
+	"errors"
 	"fmt"
 	//"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 
 	"github.com/avast/hashutil-go"
-	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // StorClientOpts is base struct
@@ -52,8 +77,57 @@ type StorClientOpts struct {
 	UpperCase bool
 	// host to s3 endpoint with bucket e.g. https://bucket.s3.eu-central-1.amazonaws.com, if is s3url set, first will be use S3, then fallback to stor
 	S3URL *url.URL
-	// template to S3 path
+	// native S3 backend (S3 API of bucket with SigV4, addressing styles, versions, requester pays)
+	// used first instead of S3URL, stor is fallback
+	// default (nil) means without native S3 backend; it can't be combined with S3URL
+	S3 *S3Config
+	// template to S3 path (key of object with S3)
 	S3Template string
+	// template to path of object on stor (relative to storage url)
+	//
+	// fields are same as in S3Template, e.g. "{{.Algorithm}}/{{.Sha}}" for store with more algorithms
+	// default ("") is "{{.Sha}}"
+	StorTemplate string
+	// hash algorithm of content address of objects (parsing, urls and verification of content)
+	// default ("") is sha256 (DefaultAlgorithm)
+	Algorithm Algorithm
+	// template to path of downloaded file (relative to downloadDir or output), Suffix is appended
+	//
+	// fields are same as in S3Template (Sha, UpperSha, FirstShaByte, SecondShaByte, ThirdShaByte, Algorithm)
+	// and Name, Priority, Tags - metadata of object provided by input (see Object)
+	// e.g. "{{.FirstShaByte}}/{{.SecondShaByte}}/{{.Sha}}" or "{{if .Name}}{{.Name}}{{else}}{{.Sha}}{{end}}"
+	//
+	// default ("") is "{{.Sha}}" ("{{.UpperSha}}" if UpperCase is set)
+	PathTemplate string
+	// manifest with record about every processed object
+	// default (nil) means without manifest
+	Manifest *Manifest
+	// destination of downloaded objects (e.g. NewTarOutput)
+	// default (nil) means files in downloadDir (NewDirOutput)
+	Output Output
+	// prometheus metrics of downloads (see NewMetrics)
+	// default (nil) means without metrics
+	Metrics *Metrics
+	// provider of tracer of spans of downloads
+	// (stor.download with children stor.queue, stor.attempt, stor.request, stor.transfer and stor.verify)
+	// default (nil) is global provider (otel.GetTracerProvider)
+	TracerProvider trace.TracerProvider
+	// callbacks of lifecycle of downloads (see Hooks)
+	// default (nil) means without hooks
+	Hooks *Hooks
+	// logger of client (see NewLogrusLogger, NewNopLogger, logzap.New, logslog.New)
+	// default (nil) is global logrus logger
+	Logger Logger
+	// propagator of trace context to headers of requests
+	// default (nil) is global propagator (otel.GetTextMapPropagator)
+	Propagator propagation.TextMapPropagator
+	// authentication of requests to stor (see BearerAuth, BasicAuth, HeaderAuth, MultiAuth)
+	// default (nil) means anonymous requests
+	StorAuth Authenticator
+	// authentication of requests to S3 (e.g. NewSigV4Signer for private bucket)
+	// default (nil) means anonymous requests; S3 with Credentials signs its requests itself,
+	// so S3Auth can't be combined with S3.Credentials (use S3.Header for custom headers)
+	S3Auth Authenticator
 }
 
 const (
@@ -62,22 +136,57 @@ const (
 	DefaultRetryAttempts = 10
 	DefaultRetryDelay    = 1e5 * time.Microsecond
 	DefaultS3Template    = "{{.FirstShaByte}}/{{.SecondShaByte}}/{{.ThirdShaByte}}/{{.Sha}}"
+	DefaultStorTemplate  = "{{.Sha}}"
+	DefaultPathTemplate  = "{{.Sha}}"
+	UpperPathTemplate    = "{{.UpperSha}}"
 )
 
 type DownPool struct {
-	input  chan hashutil.Hash
-	output chan DownStat
+	input chan downloadJob
+}
+
+type downloadJob struct {
+	object Object
+	batch  *Batch
+	// time of enqueue of job (start of download span)
+	enqueued time.Time
+}
+
+// Object is sha of object to download with optional metadata from input
+//
+// metadata are available in PathTemplate and are written to Manifest
+type Object struct {
+	Sha hashutil.Hash
+	// name of object
+	Name string
+	// subdirectory of object, it is prepended to path from PathTemplate
+	Dir string
+	// priority of object
+	Priority int
+	// tags of object
+	Tags []string
+	// original identifier of object (e.g. md5:<hex>) if sha was resolved by HashResolver
+	Origin string
+	// version of object on S3 (versionId of versioned bucket, see StorClientOpts.S3), default ("") is latest version
+	Version string
 }
 
 type StorClient struct {
-	downloadDir           string
-	storageUrl            url.URL
-	pool                  DownPool
-	total                 chan TotalStat
-	wg                    sync.WaitGroup
-	expectedDownloadCount int
-	currentDownloads      currentDownloads
-	s3template            *template.Template
+	downloadDir      string
+	storageUrl       url.URL
+	pool             DownPool
+	wg               sync.WaitGroup
+	closeOnce        sync.Once
+	// batchLock guards batch (replaced by Wait)
+	batchLock        sync.Mutex
+	batch            *Batch
+	httpClient       httpClient
+	s3               *S3Client
+	currentDownloads currentDownloads
+	progress         *progressCounters
+	s3template       *template.Template
+	stortemplate     *template.Template
+	pathTemplate     *template.Template
 	StorClientOpts
 }
 
@@ -92,24 +201,73 @@ const (
 	DOWN_OK
 )
 
+var downloadStatusNames = map[DownloadStatus]string{
+	DOWN_FAIL: "fail",
+	DOWN_SKIP: "skip",
+	DOWN_OK:   "ok",
+}
+
+func (status DownloadStatus) String() string {
+	return downloadStatusNames[status]
+}
+
 type DownStat struct {
 	Size     int64
 	Duration time.Duration
 	Status   DownloadStatus
+	// downloaded object
+	Object Object
+	// path of downloaded file (relative to downloadDir or output)
+	Path string
+	// reason of DOWN_FAIL
+	Error error
+	// backend (s3, stor) of last request of object, empty if object isn't requested
+	Backend string
+	// count of requests (attempts) by backend
+	Requests map[string]int
+	// S3 returns 404 and object is requested from stor
+	Fallback bool
 }
 
 // Size and Duration is duplicate, becuse embedding not works, because
-//   https://stackoverflow.com/questions/41686692/embedding-structs-in-golang-gives-error-unknown-field
+//
+//	https://stackoverflow.com/questions/41686692/embedding-structs-in-golang-gives-error-unknown-field
 type TotalStat struct {
 	Size     int64
 	Duration time.Duration
 	// Count of downloaded files
 	Count int
 	// Count of skipped files
-	Skip                  int
+	Skip int
+	// Count of failed files (all reasons)
+	Fail int
+	// Count of failed files which aren't on storage (404)
+	NotFound int
+	// Count of failed files with content not matching its hash
+	HashMismatch int
+	// Count of failed files because of network (connection, timeout, interrupted transfer)
+	NetworkError int
+	// Count of failed files because of server error of storage (5xx, 429 Too Many Requests)
+	ServerError int
+	// Count of repeated requests (all requests of file except first one)
+	Retries int
+	// statistics of requests by backend (s3, stor)
+	Backends map[string]BackendStat
+	// durations of downloaded files (for percentiles)
+	durations             []time.Duration
 	expectedDownloadCount int
 }
 
+// Batch is group of downloads with own statistics
+//
+// all batches of one client share its worker pool
+type Batch struct {
+	client *StorClient
+	wg     sync.WaitGroup
+	lock   sync.Mutex
+	total  TotalStat
+}
+
 var workerEnd hashutil.Hash = hashutil.Hash{}
 
 // Create new instance of stor client
@@ -147,7 +305,41 @@ func New(storUrl url.URL, downloadDir string, opts StorClientOpts) (*StorClient,
 		client.RetryAttempts = opts.RetryAttempts
 	}
 
+	client.Output = opts.Output
+	if client.Output == nil {
+		client.Output = NewDirOutput(downloadDir)
+	}
+
+	client.Manifest = opts.Manifest
+	client.Metrics = opts.Metrics
+
+	client.Hooks = opts.Hooks
+	client.Logger = opts.Logger
+	if client.Logger == nil {
+		client.Logger = defaultLogger()
+	}
+
+	client.TracerProvider = opts.TracerProvider
+	if client.TracerProvider == nil {
+		client.TracerProvider = otel.GetTracerProvider()
+	}
+
+	client.Propagator = opts.Propagator
+	if client.Propagator == nil {
+		client.Propagator = otel.GetTextMapPropagator()
+	}
+
+	client.StorAuth = opts.StorAuth
+	client.S3Auth = opts.S3Auth
+
 	client.S3URL = opts.S3URL
+	client.S3 = opts.S3
+	if client.S3 != nil && client.S3URL != nil {
+		return nil, fmt.Errorf("S3URL and S3 can't be combined")
+	}
+	if client.S3 != nil && client.S3.Credentials != nil && client.S3Auth != nil {
+		return nil, fmt.Errorf("S3Auth and S3.Credentials can't be combined (requests would be signed twice)")
+	}
 	if opts.S3Template == "" {
 		opts.S3Template = DefaultS3Template
 	}
@@ -158,12 +350,52 @@ func New(storUrl url.URL, downloadDir string, opts StorClientOpts) (*StorClient,
 	}
 	client.s3template = tmpl
 
+	client.StorTemplate = opts.StorTemplate
+	if client.StorTemplate == "" {
+		client.StorTemplate = DefaultStorTemplate
+	}
+	storTmpl, err := template.New("stortemplate").Parse(client.StorTemplate)
+	if err != nil {
+		return nil, err
+	}
+	client.stortemplate = storTmpl
+
+	if client.Algorithm, err = ParseAlgorithm(string(opts.Algorithm)); err != nil {
+		return nil, err
+	}
+
+	client.PathTemplate = opts.PathTemplate
+	if client.PathTemplate == "" && client.UpperCase {
+		client.PathTemplate = UpperPathTemplate
+	} else if client.PathTemplate == "" {
+		client.PathTemplate = DefaultPathTemplate
+	}
+	pathTmpl, err := template.New("pathtemplate").Parse(client.PathTemplate)
+	if err != nil {
+		return nil, err
+	}
+	client.pathTemplate = pathTmpl
+
 	downloadPool := DownPool{
-		input:  make(chan hashutil.Hash, 1024),
-		output: make(chan DownStat, 1024),
+		input: make(chan downloadJob, 1024),
 	}
 
 	client.pool = downloadPool
+	client.progress = &progressCounters{}
+	httpClient := client.newHTTPClient()
+	s3Auth := client.S3Auth
+	if client.S3 != nil {
+		if client.s3, err = newS3Client(*client.S3, httpClient); err != nil {
+			return nil, err
+		}
+		// downloads and requests of S3 API (stat, presign) share authentication
+		client.s3.auth = client.S3Auth
+		s3Auth = client.s3
+	}
+
+	authClient := newAuthHTTPClient(httpClient, map[string]Authenticator{backendStor: client.StorAuth, backendS3: s3Auth})
+	client.httpClient = tracingHTTPClient{httpClient: authClient, propagator: client.Propagator}
+	client.batch = client.NewBatch()
 
 	return &client, nil
 }
@@ -172,66 +404,164 @@ func New(storUrl url.URL, downloadDir string, opts StorClientOpts) (*StorClient,
 func (client *StorClient) Start() {
 	for id := 0; id < client.Max; id++ {
 		client.wg.Add(1)
-		go client.downloadWorker(id, client.newHTTPClient, client.pool.input, client.pool.output)
-	}
-
-	client.total = make(chan TotalStat, 1)
-	go client.processStats(client.pool.output, client.total)
-}
-
-func (client *StorClient) processStats(downloadStats <-chan DownStat, totalStat chan<- TotalStat) {
-	total := TotalStat{}
-	for stat := range downloadStats {
-		total.Size += stat.Size
-		total.Duration += stat.Duration
-		if stat.Status == DOWN_SKIP {
-			total.Skip++
-		} else if stat.Status == DOWN_OK {
-			total.Count++
-		}
+		go client.downloadWorker(id, client.sharedHTTPClient, client.pool.input)
 	}
-
-	total.expectedDownloadCount = client.expectedDownloadCount
-
-	totalStat <- total
 }
 
 // add sha to douwnload queue
 func (client *StorClient) Download(sha hashutil.Hash) {
-	client.expectedDownloadCount++
-	client.pool.input <- sha
+	client.DownloadObject(Object{Sha: sha})
+}
+
+// add object (sha with metadata) to douwnload queue
+func (client *StorClient) DownloadObject(object Object) {
+	// lock is held until object is queued, so Wait can't miss it
+	client.batchLock.Lock()
+	defer client.batchLock.Unlock()
+
+	client.batch.DownloadObject(object)
 }
 
-// wait to all downloads
+// wait to all downloads added by Download
 // return download stats
+//
+// client can be used for next downloads after Wait,
+// downloads added concurrently with Wait belong to next Wait
 func (client *StorClient) Wait() TotalStat {
-	client.sendEndSignalToAllWorkers()
+	client.batchLock.Lock()
+	batch := client.batch
+	client.batch = client.NewBatch()
+	client.batchLock.Unlock()
 
-	client.wg.Wait()
-	close(client.pool.output)
+	return batch.Wait()
+}
+
+// Close stops all workers and closes output
+//
+// client can't be used after Close
+func (client *StorClient) Close() error {
+	var err error
+	client.closeOnce.Do(func() {
+		client.sendEndSignalToAllWorkers()
+		client.wg.Wait()
+
+		err = client.Output.Close()
+	})
 
-	return <-client.total
+	return err
 }
 
 func (client *StorClient) sendEndSignalToAllWorkers() {
 	for i := 0; i < client.Max; i++ {
-		client.pool.input <- workerEnd
+		client.pool.input <- downloadJob{object: Object{Sha: workerEnd}}
+	}
+}
+
+// NewBatch create new batch of downloads
+//
+// batch uses worker pool of client, so client must be started
+func (client *StorClient) NewBatch() *Batch {
+	return &Batch{client: client}
+}
+
+// add sha to download queue of batch
+func (batch *Batch) Download(sha hashutil.Hash) {
+	batch.DownloadObject(Object{Sha: sha})
+}
+
+// add object (sha with metadata) to download queue of batch
+func (batch *Batch) DownloadObject(object Object) {
+	batch.lock.Lock()
+	batch.total.expectedDownloadCount++
+	batch.lock.Unlock()
+
+	batch.wg.Add(1)
+	atomic.AddInt64(&batch.client.progress.queued, 1)
+	batch.client.Metrics.enqueue()
+	batch.client.Hooks.queued(object)
+	batch.client.pool.input <- downloadJob{object: object, batch: batch, enqueued: time.Now()}
+}
+
+// wait to all downloads of batch
+// return download stats of batch
+func (batch *Batch) Wait() TotalStat {
+	batch.wg.Wait()
+
+	batch.lock.Lock()
+	defer batch.lock.Unlock()
+
+	return batch.total.clone()
+}
+
+func (batch *Batch) done(stat DownStat) {
+	batch.lock.Lock()
+	batch.total.add(stat)
+	batch.lock.Unlock()
+
+	batch.wg.Done()
+}
+
+func (total *TotalStat) add(stat DownStat) {
+	total.Size += stat.Size
+	total.Duration += stat.Duration
+	switch stat.Status {
+	case DOWN_SKIP:
+		total.Skip++
+	case DOWN_OK:
+		total.Count++
+		total.durations = append(total.durations, stat.Duration)
+	default:
+		total.Fail++
+		switch {
+		case errors.Is(stat.Error, ErrNotFound):
+			total.NotFound++
+		case errors.Is(stat.Error, ErrHashMismatch):
+			total.HashMismatch++
+		case isNetworkError(stat.Error):
+			total.NetworkError++
+		case isServerError(stat.Error):
+			total.ServerError++
+		}
 	}
+
+	total.addRequests(stat)
 }
 
-// format and log total stats
+// format and log total stats to global logrus logger (see Log)
 func (total TotalStat) Print(startTime time.Time) {
+	total.Log(defaultLogger(), startTime)
+}
+
+// Log formats and logs total stats to logger
+func (total TotalStat) Log(logger Logger, startTime time.Time) {
 	var totalSizeMB float64 = (float64)(total.Size) / (1024 * 1024)
 	totalDuration := time.Since(startTime)
 
-	log.WithFields(log.Fields{
+	fields := Fields{
 		"total download size":                 fmt.Sprintf("%0.3fMB", totalSizeMB),
 		"total time":                          fmt.Sprintf("%0.3fs", totalDuration.Seconds()),
 		"download rate":                       fmt.Sprintf("%0.3fMB/s", totalSizeMB/totalDuration.Seconds()),
 		"expected count of files to download": total.expectedDownloadCount,
 		"downloaded files":                    total.Count,
 		"skipped files":                       total.Skip,
-	}).Info("statistics")
+		"failed files":                        total.Fail,
+		"not found files":                     total.NotFound,
+		"hash mismatch files":                 total.HashMismatch,
+		"network error files":                 total.NetworkError,
+		"server error files":                  total.ServerError,
+		"retries":                             total.Retries,
+		"p50 download time":                   fmt.Sprintf("%0.3fs", total.Percentile(50).Seconds()),
+		"p95 download time":                   fmt.Sprintf("%0.3fs", total.Percentile(95).Seconds()),
+		"p99 download time":                   fmt.Sprintf("%0.3fs", total.Percentile(99).Seconds()),
+	}
+
+	for backend, stat := range total.Backends {
+		fields[backend+" download size"] = fmt.Sprintf("%0.3fMB", float64(stat.Size)/(1024*1024))
+		fields[backend+" requests"] = stat.Requests
+		fields[backend+" fallbacks"] = stat.Fallbacks
+	}
+
+	logger.Info("statistics", fields)
 }
 
 // Status return true if all files are downloaded
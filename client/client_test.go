@@ -1,10 +1,15 @@
 package storclient_test
 
 import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/avast/hashutil-go"
 	"github.com/avast/stor-client/client"
 	"github.com/stretchr/testify/assert"
 )
@@ -30,3 +35,79 @@ func TestNewInfinityTimeout(t *testing.T) {
 	expectedTimeout, _ := time.ParseDuration("0s")
 	assert.Equal(t, client.Timeout, expectedTimeout)
 }
+
+func TestBatches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	url, _ := url.Parse(server.URL)
+
+	client, err := storclient.New(*url, "some_dir", storclient.StorClientOpts{Devnull: true})
+	assert.NoError(t, err)
+
+	client.Start()
+	defer func() {
+		assert.NoError(t, client.Close())
+	}()
+
+	emptyHash := hashutil.EmptyHash(sha256.New())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			batch := client.NewBatch()
+			batch.Download(emptyHash)
+			total := batch.Wait()
+
+			assert.True(t, total.Status())
+			assert.Equal(t, 1, total.Count+total.Skip)
+		}()
+	}
+	wg.Wait()
+
+	client.Download(emptyHash)
+	assert.True(t, client.Wait().Status())
+
+	client.Download(emptyHash)
+	assert.True(t, client.Wait().Status(), "client is reusable after Wait")
+}
+
+func TestConcurrentWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	url, _ := url.Parse(server.URL)
+
+	client, err := storclient.New(*url, "some_dir", storclient.StorClientOpts{Devnull: true})
+	assert.NoError(t, err)
+
+	client.Start()
+	defer func() {
+		assert.NoError(t, client.Close())
+	}()
+
+	emptyHash := hashutil.EmptyHash(sha256.New())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 50; i++ {
+			client.Download(emptyHash)
+		}
+	}()
+
+	// downloads added concurrently with Wait belong to this or next Wait, none is lost
+	var expected int
+	for i := 0; i < 10; i++ {
+		expected += client.Wait().Summary(0).Expected
+	}
+	wg.Wait()
+	expected += client.Wait().Summary(0).Expected
+
+	assert.Equal(t, 50, expected)
+}
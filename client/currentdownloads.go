@@ -2,8 +2,6 @@ package storclient
 
 import (
 	"sync"
-
-	"github.com/avast/hashutil-go"
 )
 
 type currentDownloads struct {
@@ -11,37 +9,37 @@ type currentDownloads struct {
 	hashmap map[string]interface{}
 }
 
-// Del delete hash from actualdownloads
-func (a *currentDownloads) Del(hash hashutil.Hash) {
+// Del delete name (of downloaded object) from actualdownloads
+func (a *currentDownloads) Del(name string) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	delete(a.hashmap, hash.String())
+	delete(a.hashmap, name)
 }
 
-// ContainsOrAdd check if hash is in actualdownloads and if not added him
-// returns true if are hash added
-func (a *currentDownloads) ContainsOrAdd(hash hashutil.Hash) bool {
+// ContainsOrAdd check if name is in actualdownloads and if not added him
+// returns true if are name added
+func (a *currentDownloads) ContainsOrAdd(name string) bool {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	if !a.contains(hash) {
-		a.add(hash)
+	if !a.contains(name) {
+		a.add(name)
 		return true
 	}
 
 	return false
 }
 
-func (a *currentDownloads) contains(hash hashutil.Hash) bool {
-	_, ok := a.hashmap[hash.String()]
+func (a *currentDownloads) contains(name string) bool {
+	_, ok := a.hashmap[name]
 	return ok
 }
 
-func (a *currentDownloads) add(hash hashutil.Hash) {
+func (a *currentDownloads) add(name string) {
 	if a.hashmap == nil {
 		a.hashmap = make(map[string]interface{})
 	}
 
-	a.hashmap[hash.String()] = nil
+	a.hashmap[name] = nil
 }
@@ -11,7 +11,7 @@ import (
 func TestCurrentDownloads(t *testing.T) {
 	var cur currentDownloads
 
-	hash := hashutil.EmptyHash(md5.New())
+	hash := hashutil.EmptyHash(md5.New()).String()
 
 	assert.True(t, cur.ContainsOrAdd(hash))
 	assert.False(t, cur.ContainsOrAdd(hash))
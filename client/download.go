@@ -2,12 +2,13 @@ package storclient
 
 import (
 	"bytes"
-	"crypto/sha256"
+	"context"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -15,11 +16,11 @@ import (
 	"github.com/avast/hashutil-go"
 	"github.com/avast/retry-go"
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type httpClient interface {
-	Get(url string) (*http.Response, error)
+	Do(req *http.Request) (*http.Response, error)
 }
 
 //type logFieldsError interface {
@@ -27,10 +28,14 @@ type httpClient interface {
 //	LogFields() log.Fields
 //}
 
-type downloadError struct {
-	sha        hashutil.Hash
-	statusCode int
-	status     string
+// requestStat is statistic of attempts of retryWithFallback
+type requestStat struct {
+	// backend (s3, stor) of last attempt
+	backend string
+	// count of attempts by backend
+	requests map[string]int
+	// S3 returns 404 and stor is used
+	fallback bool
 }
 
 type successDownload struct {
@@ -38,10 +43,6 @@ type successDownload struct {
 	lastModified time.Time
 }
 
-func (err downloadError) Error() string {
-	return fmt.Sprintf("Download of %s fail %d (%s)", err.sha, err.statusCode, err.status)
-}
-
 //func (err downloadError) LogFields() log.Fields {
 //	return log.Fields{
 //		"sha256":     err.sha.String(),
@@ -50,140 +51,220 @@ func (err downloadError) Error() string {
 //	}
 //}
 
-func (client *StorClient) downloadWorker(id int, httpClientFunc func() httpClient, shasForDownload <-chan hashutil.Hash, downloadedFilesStat chan<- DownStat) {
+func (client *StorClient) downloadWorker(id int, httpClientFunc func() httpClient, jobs <-chan downloadJob) {
 	defer client.wg.Done()
 
-	log.WithField("worker", id).Debugln("Start download worker...")
+	client.Logger.Debug("Start download worker...", Fields{fieldWorker: id})
 
-	for sha := range shasForDownload {
-		if sha.Equal(workerEnd) {
-			log.WithField("worker", id).Debugln("worker end")
+	for job := range jobs {
+		if job.object.Sha.Equal(workerEnd) {
+			client.Logger.Debug("worker end", Fields{fieldWorker: id})
 			return
 		}
 
-		filename := sha.String()
-		if client.UpperCase {
-			filename = strings.ToUpper(sha.String())
-		}
+		client.Metrics.dequeue()
+		client.progress.start()
+		client.Hooks.start(job.object, id)
+
+		ctx, span := client.tracer().Start(context.Background(), spanDownload,
+			trace.WithTimestamp(job.enqueued),
+			trace.WithAttributes(attrSha.String(job.object.Sha.String())),
+		)
+		_, queueSpan := client.tracer().Start(ctx, spanQueue, trace.WithTimestamp(job.enqueued))
+		queueSpan.End()
 
-		filename += client.Suffix
+		stat := client.download(ctx, id, httpClientFunc, job.object)
+		stat.Object = job.object
 
-		filepath, err := pathutil.New(client.downloadDir, filename)
-		if err != nil {
-			log.Errorf("path problem: %s", err)
+		span.SetAttributes(attrStatus.String(stat.Status.String()), attrSize.Int64(stat.Size))
+		endSpan(span, stat.Error)
 
-			downloadedFilesStat <- DownStat{Status: DOWN_FAIL}
+		client.progress.finish(stat)
+		client.Metrics.observeDownload(stat)
+		client.Hooks.finish(stat)
+
+		if client.Manifest != nil {
+			entry := NewManifestEntry(stat)
+			if client.Algorithm != DefaultAlgorithm {
+				entry.Algorithm = string(client.Algorithm)
+			}
 
-			continue
+			if err := client.Manifest.WriteEntry(entry); err != nil {
+				client.Logger.Error(fmt.Sprintf("Write to manifest fail: %s", err), Fields{fieldWorker: id, fieldSha: job.object.Sha.String()})
+			}
 		}
 
-		if filepath.Exists() {
-			log.WithFields(log.Fields{
-				"worker": id,
-				"sha256": sha.String(),
-			}).Debugf("File %s exists - skip download", filepath)
+		job.batch.done(stat)
+	}
+}
 
-			downloadedFilesStat <- DownStat{Status: DOWN_SKIP}
+func (client *StorClient) download(ctx context.Context, id int, httpClientFunc func() httpClient, object Object) DownStat {
+	sha := object.Sha
+	fields := Fields{
+		fieldWorker: id,
+		fieldSha:    sha.String(),
+	}
 
-			continue
-		}
+	filename, err := client.createFilePath(object)
+	if err != nil {
+		client.Logger.Error(fmt.Sprintf("path problem: %s", err), fields)
 
-		if !client.currentDownloads.ContainsOrAdd(sha) {
-			log.WithFields(log.Fields{
-				"worker": id,
-				"sha256": sha.String(),
-			}).Debug("File is now downloading in other worker - skip download")
+		return DownStat{Status: DOWN_FAIL, Error: err}
+	}
 
-			downloadedFilesStat <- DownStat{Status: DOWN_SKIP}
+	if client.Output.Exists(filename) {
+		client.Logger.Debug(fmt.Sprintf("File %s exists - skip download", filename), fields)
 
-			continue
-		}
+		return DownStat{Status: DOWN_SKIP, Path: filename}
+	}
+
+	if !client.currentDownloads.ContainsOrAdd(filename) {
+		client.Logger.Debug("File is now downloading in other worker - skip download", fields)
+
+		return DownStat{Status: DOWN_SKIP, Path: filename}
+	}
 
-		startTime := time.Now()
+	client.Metrics.startDownload()
+	startTime := time.Now()
 
-		tryS3 := false
-		if client.S3URL != nil {
-			tryS3 = true
+	var size int64
+	requests, err := client.retryWithFallback(ctx, fields, object, client.Hooks, func(ctx context.Context, u string) error {
+		var err error
+
+		httpClient := client.Hooks.withProgress(httpClientFunc(), object)
+		if client.Devnull {
+			size, err = downloadFileToDevnull(ctx, httpClient, u, sha, client.Algorithm)
+		} else {
+			size, err = downloadFileViaTempFile(ctx, httpClient, client.Output, filename, u, sha, client.Algorithm)
 		}
 
-		var size int64
-		err = retry.Do(
-			func() error {
-				var err error
-
-				var u string
-				if tryS3 {
-					var urlErr error
-					u, urlErr = client.createS3URL(sha)
-					if urlErr != nil {
-						log.WithFields(log.Fields{
-							"worker": id,
-							"sha256": sha.String(),
-						}).Warningf("S3 template fail: %s", urlErr)
-					} else {
-						log.WithFields(log.Fields{
-							"worker": id,
-							"sha256": sha.String(),
-						}).Debugf("Use S3 url %s", u)
-					}
+		return err
+	})
+
+	downloadDuration := time.Since(startTime)
+	client.currentDownloads.Del(filename)
+	client.Metrics.finishDownload()
+
+	if err != nil {
+		client.Logger.Error(fmt.Sprintf("Error download %s: %s", sha, err), fields.with(fieldBackend, requests.backend).with(fieldError, err))
+
+		return DownStat{Status: DOWN_FAIL, Path: filename, Error: err, Backend: requests.backend, Requests: requests.requests, Fallback: requests.fallback}
+	}
+
+	client.Logger.Debug(fmt.Sprintf("Downloaded %s", sha), fields.with(fieldBackend, requests.backend))
+
+	return DownStat{Size: size, Duration: downloadDuration, Status: DOWN_OK, Path: filename, Backend: requests.backend, Requests: requests.requests, Fallback: requests.fallback}
+}
+
+// retryWithFallback calls download with url of object until success
+//
+// S3 url is used first (if S3URL or S3 is set), stor url is used as fallback if S3 returns 404
+// (or 403 of bucket of S3, it returns AccessDenied for missing key without permission to list bucket),
+// 404 from stor or cancelled ctx stops retrying
+//
+// every attempt is traced as stor.attempt span (child of span in ctx), download gets context of attempt span
+// with backend of url (requests are authenticated by Authenticator of backend)
+//
+// attempts are reported to hooks (nil means without hooks)
+//
+// returns statistic of attempts (backends, fallback)
+func (client *StorClient) retryWithFallback(ctx context.Context, fields Fields, object Object, hooks *Hooks, download func(ctx context.Context, url string) error) (requestStat, error) {
+	sha := object.Sha
+	tryS3 := false
+	if client.S3URL != nil || client.s3 != nil {
+		tryS3 = true
+	}
+
+	stat := requestStat{requests: map[string]int{}}
+	var backend string
+	attempts := 0
+	err := retry.Do(
+		func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			attempts++
+
+			var u string
+			if tryS3 {
+				var urlErr error
+				u, urlErr = client.createS3URL(object)
+				if urlErr != nil {
+					client.Logger.Warn(fmt.Sprintf("S3 template fail: %s", urlErr), fields.with(fieldAttempt, attempts))
+				} else {
+					backend = backendS3
+					client.Logger.Debug(fmt.Sprintf("Use S3 url %s", u), fields.with(fieldAttempt, attempts).with(fieldBackend, backend))
 				}
-				if u == "" {
-					u = client.createStorURL(sha)
-					log.WithFields(log.Fields{
-						"worker": id,
-						"sha256": sha.String(),
-					}).Debugf("Use Stor url %s", u)
+			}
+			if u == "" {
+				var urlErr error
+				if u, urlErr = client.createStorURL(sha); urlErr != nil {
+					return urlErr
 				}
+				backend = backendStor
+				client.Logger.Debug(fmt.Sprintf("Use Stor url %s", u), fields.with(fieldAttempt, attempts).with(fieldBackend, backend))
+			}
 
-				if client.Devnull {
-					size, err = downloadFileToDevnull(httpClientFunc(), u, sha)
-				} else {
-					size, err = downloadFileViaTempFile(httpClientFunc(), filepath, u, sha)
+			stat.backend = backend
+			stat.requests[backend]++
+			hooks.attempt(object, attempts, backend, u)
+			if attempts > 1 {
+				client.Metrics.retry(backend)
+			}
+
+			attemptCtx, span := client.tracer().Start(withBackend(ctx, backend), spanAttempt, trace.WithAttributes(
+				attrAttempt.Int(attempts),
+				attrBackend.String(backend),
+				attrURL.String(u),
+			))
+
+			startTime := time.Now()
+			err := download(attemptCtx, u)
+			client.Metrics.observeRequest(backend, time.Since(startTime))
+			endSpan(span, err)
+
+			var statusErr *HTTPStatusError
+			if errors.As(err, &statusErr) {
+				statusErr.Backend = backend
+			}
+
+			return err
+		},
+		retry.OnRetry(func(n uint, err error) {
+			client.Logger.Debug(fmt.Sprintf("Retry #%d: %s", n, err), fields.with(fieldAttempt, attempts).with(fieldBackend, backend))
+		}),
+		retry.RetryIf(func(err error) bool {
+			if ctx.Err() != nil {
+				return false
+			}
+
+			if errors.Is(err, ErrNotFound) || (tryS3 && client.s3 != nil && isAccessDenied(err)) {
+				if !tryS3 {
+					return false
 				}
 
-				return err
-			},
-			retry.OnRetry(func(n uint, err error) {
-				log.WithFields(log.Fields{
-					"worker": id,
-					"sha256": sha.String(),
-				}).Debugf("Retry #%d: %s", n, err)
-			}),
-			retry.RetryIf(func(err error) bool {
-				switch e := err.(type) {
-				case downloadError:
-					if (downloadError)(e).statusCode == 404 && tryS3 {
-						tryS3 = false
-					} else if (downloadError)(e).statusCode == 404 {
-						return false
-					}
+				tryS3 = false
+				stat.fallback = true
+				if attempts < int(client.RetryAttempts) {
+					hooks.fallback(object, backendS3, backendStor)
 				}
 
 				return true
-			}),
-			retry.Delay(client.RetryDelay),
-			retry.Attempts(client.RetryAttempts),
-			retry.Units(1),
-		)
+			}
 
-		downloadDuration := time.Since(startTime)
-		client.currentDownloads.Del(sha)
+			if attempts < int(client.RetryAttempts) {
+				hooks.retry(object, attempts, err)
+			}
 
-		if err != nil {
-			log.WithFields(log.Fields{
-				"worker": id,
-				"sha256": sha.String(),
-				"error":  err,
-			}).Errorf("Error download %s: %s\n", sha, err)
-			downloadedFilesStat <- DownStat{Status: DOWN_FAIL}
-		} else {
-			log.WithFields(log.Fields{
-				"worker": id,
-				"sha256": sha.String(),
-			}).Debugf("Downloaded %s", sha)
-			downloadedFilesStat <- DownStat{Size: size, Duration: downloadDuration, Status: DOWN_OK}
-		}
-	}
+			return true
+		}),
+		retry.Delay(client.RetryDelay),
+		retry.Attempts(client.RetryAttempts),
+		retry.Units(1),
+	)
+
+	return stat, retryResult(ctx, err)
 }
 
 func (client *StorClient) newHTTPClient() httpClient {
@@ -195,37 +276,107 @@ func (client *StorClient) newHTTPClient() httpClient {
 	return &http.Client{Transport: tr}
 }
 
-func (client *StorClient) createS3URL(sha hashutil.Hash) (string, error) {
+// all workers and batches share one http client (and transport)
+func (client *StorClient) sharedHTTPClient() httpClient {
+	return client.httpClient
+}
+
+// templateParams are fields of S3Template and PathTemplate
+type templateParams struct {
+	Sha, UpperSha, FirstShaByte, SecondShaByte, ThirdShaByte string
+	Algorithm                                                string
+	Name                                                     string
+	Priority                                                 int
+	Tags                                                     []string
+}
+
+func (client *StorClient) newTemplateParams(object Object) templateParams {
+	shaStr := object.Sha.String()
+
+	return templateParams{
+		Sha:           shaStr,
+		UpperSha:      strings.ToUpper(shaStr),
+		FirstShaByte:  shaStr[0:2],
+		SecondShaByte: shaStr[2:4],
+		ThirdShaByte:  shaStr[4:6],
+		Algorithm:     string(client.Algorithm),
+		Name:          object.Name,
+		Priority:      object.Priority,
+		Tags:          object.Tags,
+	}
+}
+
+// createS3Key returns path (key) of object on S3 by S3Template
+func (client *StorClient) createS3Key(object Object) (string, error) {
+	var pathBytes bytes.Buffer
+	if err := client.s3template.Execute(&pathBytes, client.newTemplateParams(Object{Sha: object.Sha})); err != nil {
+		return "", err
+	}
+
+	return pathBytes.String(), nil
+}
+
+// createS3URL returns url of object on S3 (S3URL or bucket of S3 with version of object)
+func (client *StorClient) createS3URL(object Object) (string, error) {
+	key, err := client.createS3Key(object)
+	if err != nil {
+		return "", err
+	}
+
+	if client.s3 != nil {
+		return client.s3.ObjectURL(key, object.Version), nil
+	}
+
+	return fmt.Sprintf("%s/%s", client.S3URL.String(), key), nil
+}
+
+// createFilePath returns path of downloaded file (by Dir of object, PathTemplate and Suffix)
+// path must be relative and must not leave download dir
+func (client *StorClient) createFilePath(object Object) (string, error) {
 	var pathBytes bytes.Buffer
-	shaStr := sha.String()
-	params := struct{ Sha, FirstShaByte, SecondShaByte, ThirdShaByte string }{shaStr, shaStr[0:2], shaStr[2:4], shaStr[4:6]}
-	if err := client.s3template.Execute(&pathBytes, params); err != nil {
+	if err := client.pathTemplate.Execute(&pathBytes, client.newTemplateParams(object)); err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf("%s/%s", client.S3URL.String(), pathBytes.String()), nil
+	path := filepath.Join(object.Dir, pathBytes.String()+client.Suffix)
+	clean := filepath.ToSlash(filepath.Clean(path))
+	if path == "" || filepath.IsAbs(path) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid path %q", path)
+	}
+
+	return clean, nil
 }
 
-func (client *StorClient) createStorURL(sha hashutil.Hash) string {
+func (client *StorClient) createStorURL(sha hashutil.Hash) (string, error) {
+	var pathBytes bytes.Buffer
+	if err := client.stortemplate.Execute(&pathBytes, client.newTemplateParams(Object{Sha: sha})); err != nil {
+		return "", err
+	}
+
 	storage := (client.storageUrl).String()
 	storage = strings.TrimRight(storage, "/")
-	return fmt.Sprintf("%s/%s", storage, sha)
+	return fmt.Sprintf("%s/%s", storage, pathBytes.String()), nil
 }
 
-func downloadFileToDevnull(httpClient httpClient, url string, expectedSha hashutil.Hash) (size int64, err error) {
-	succ, err := downloadFileToWriter(httpClient, url, ioutil.Discard, expectedSha)
+func downloadFileToDevnull(ctx context.Context, httpClient httpClient, url string, expectedSha hashutil.Hash, algorithm Algorithm) (size int64, err error) {
+	succ, err := downloadFileToWriter(ctx, httpClient, url, ioutil.Discard, expectedSha, algorithm)
 	return succ.size, err
 }
 
-func downloadFileViaTempFile(httpClient httpClient, filepath pathutil.Path, url string, expectedSha hashutil.Hash) (size int64, err error) {
-	temppath, err := pathutil.NewTempFile(pathutil.TempOpt{Dir: filepath.Parent().Canonpath(), Prefix: fmt.Sprintf("%s_*.temp", expectedSha)})
+func downloadFileViaTempFile(ctx context.Context, httpClient httpClient, output Output, name string, url string, expectedSha hashutil.Hash, algorithm Algorithm) (size int64, err error) {
+	tempdir, err := output.TempDir(name)
+	if err != nil {
+		return 0, err
+	}
+
+	temppath, err := pathutil.NewTempFile(pathutil.TempOpt{Dir: tempdir, Prefix: fmt.Sprintf("%s_*.temp", expectedSha)})
 	if err != nil {
 		return 0, errors.Wrap(err, "Construct of new temp file fail")
 	}
 
 	// cleanup tempfile if this function fail (err is set)
 	defer func() {
-		if err != nil {
+		if err != nil && temppath.Exists() {
 			if remErr := temppath.Remove(); remErr != nil {
 				err = errors.Wrapf(remErr, "Cleanup tempfile %s fail", temppath)
 			}
@@ -238,23 +389,19 @@ func downloadFileViaTempFile(httpClient httpClient, filepath pathutil.Path, url
 		}
 	}
 
-	succ, err := downloadFile(httpClient, temppath, url, expectedSha)
+	succ, err := downloadFile(ctx, httpClient, temppath, url, expectedSha, algorithm)
 	if err != nil {
 		return 0, err
 	}
 
-	if _, err := temppath.Rename(filepath.Canonpath()); err != nil {
-		return 0, errors.Wrapf(err, "Rename temp %s to final path %s fail", temppath, filepath)
-	}
-
-	if err = os.Chtimes(filepath.Canonpath(), succ.lastModified, succ.lastModified); err != nil {
-		return 0, errors.Wrapf(err, "Chtimes(%s, %s) fail", filepath.Canonpath(), succ.lastModified.String())
+	if err = output.Store(name, temppath, succ.lastModified); err != nil {
+		return 0, err
 	}
 
 	return succ.size, nil
 }
 
-func downloadFile(httpClient httpClient, path pathutil.Path, url string, expectedSha hashutil.Hash) (succ successDownload, err error) {
+func downloadFile(ctx context.Context, httpClient httpClient, path pathutil.Path, url string, expectedSha hashutil.Hash, algorithm Algorithm) (succ successDownload, err error) {
 	out, err := path.OpenWriter()
 	if err != nil {
 		return successDownload{}, errors.Wrapf(err, "OpenWriter to tempfile %s fail", path)
@@ -266,11 +413,11 @@ func downloadFile(httpClient httpClient, path pathutil.Path, url string, expecte
 		}
 	}()
 
-	return downloadFileToWriter(httpClient, url, out, expectedSha)
+	return downloadFileToWriter(ctx, httpClient, url, out, expectedSha, algorithm)
 }
 
-func downloadFileToWriter(httpClient httpClient, url string, out io.Writer, expectedSha hashutil.Hash) (succ successDownload, err error) {
-	resp, err := httpClient.Get(url)
+func downloadFileToWriter(ctx context.Context, httpClient httpClient, url string, out io.Writer, expectedSha hashutil.Hash, algorithm Algorithm) (succ successDownload, err error) {
+	resp, err := getObject(ctx, httpClient, url, expectedSha)
 	if err != nil {
 		return successDownload{}, err
 	}
@@ -280,44 +427,83 @@ func downloadFileToWriter(httpClient httpClient, url string, out io.Writer, expe
 		}
 	}()
 
-	if resp.StatusCode != 200 {
-		return successDownload{}, downloadError{sha: expectedSha, statusCode: resp.StatusCode, status: resp.Status}
-	}
-
 	lastModified, err := getLastModifiedTime(resp)
 	if err != nil {
 		return successDownload{}, err
 	}
 
-	hasher := sha256.New()
+	hasher := algorithm.New()
 	multi := io.MultiWriter(out, hasher)
 
+	_, transferSpan := startSpan(ctx, spanTransfer)
 	size, err := io.Copy(multi, resp.Body)
+	transferSpan.SetAttributes(attrSize.Int64(size))
+	endSpan(transferSpan, err)
 	if err != nil {
 		return successDownload{}, err
 	}
 
-	downSha256, err := hashutil.BytesToHash(sha256.New(), hasher.Sum(nil))
+	_, verifySpan := startSpan(ctx, spanVerify)
+	err = verifySha(hasher, expectedSha)
+	endSpan(verifySpan, err)
 	if err != nil {
 		return successDownload{}, err
 	}
 
-	if !downSha256.Equal(expectedSha) {
-		return successDownload{}, fmt.Errorf("Downloaded sha (%s) is not equal with expected sha (%s)", downSha256, expectedSha)
-	}
-
 	return successDownload{
 		size:         size,
 		lastModified: lastModified,
 	}, nil
 }
 
+// getObject returns response of successful (200) GET request,
+// other status codes are returned as *HTTPStatusError
+func getObject(ctx context.Context, httpClient httpClient, url string, expectedSha hashutil.Hash) (*http.Response, error) {
+	return requestObject(ctx, httpClient, http.MethodGet, url, expectedSha)
+}
+
+// requestObject returns response of successful (200) request of method,
+// other status codes are returned as *HTTPStatusError
+func requestObject(ctx context.Context, httpClient httpClient, method string, url string, expectedSha hashutil.Hash) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		if errClose := resp.Body.Close(); errClose != nil {
+			return nil, errClose
+		}
+
+		return nil, &HTTPStatusError{Sha: expectedSha, Method: method, URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	return resp, nil
+}
+
+func verifySha(hasher hash.Hash, expectedSha hashutil.Hash) error {
+	downSha, err := hashutil.BytesToHash(hasher, hasher.Sum(nil))
+	if err != nil {
+		return err
+	}
+
+	if !downSha.Equal(expectedSha) {
+		return hashMismatchError{expected: expectedSha, downloaded: downSha}
+	}
+
+	return nil
+}
+
 func getLastModifiedTime(resp *http.Response) (time.Time, error) {
 	lastModified := time.Now()
 	var err error
 
 	if lastModifiedStr := resp.Header.Get("Last-Modified"); lastModifiedStr != "" {
-		log.Info(lastModifiedStr)
 		lastModified, err = http.ParseTime(lastModifiedStr)
 		if err != nil {
 			return lastModified, err
@@ -1,10 +1,14 @@
 package storclient
 
 import (
+	"archive/tar"
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"io"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -31,7 +35,7 @@ type clientMock struct {
 	header     http.Header
 }
 
-func (c *clientMock) Get(url string) (*http.Response, error) {
+func (c *clientMock) Do(req *http.Request) (*http.Response, error) {
 	var body bodyMock
 
 	return &http.Response{StatusCode: c.statusCode, Status: c.status, Body: body, Header: c.header}, nil
@@ -42,7 +46,7 @@ type clientMockWithDelay struct {
 	status     string
 }
 
-func (c *clientMockWithDelay) Get(url string) (*http.Response, error) {
+func (c *clientMockWithDelay) Do(req *http.Request) (*http.Response, error) {
 	var body bodyMock
 
 	time.Sleep(time.Millisecond)
@@ -55,11 +59,11 @@ var emptyHash = hashutil.EmptyHash(sha256.New())
 func TestDownloadFile(t *testing.T) {
 	client := &clientMock{}
 
-	_, err := downloadFileToDevnull(client, "http://blabla", emptyHash)
+	_, err := downloadFileToDevnull(context.Background(), client, "http://blabla", emptyHash, AlgorithmSHA256)
 	assert.Error(t, err)
 
 	client = &clientMock{statusCode: 200, status: "OK"}
-	_, err = downloadFileToDevnull(client, "http://blabla", emptyHash)
+	_, err = downloadFileToDevnull(context.Background(), client, "http://blabla", emptyHash, AlgorithmSHA256)
 	assert.NoError(t, err)
 
 	path, err := pathutil.NewTempFile(pathutil.TempOpt{})
@@ -67,7 +71,7 @@ func TestDownloadFile(t *testing.T) {
 	assert.NoError(t, path.Remove())
 
 	client = &clientMock{statusCode: 200, status: "OK"}
-	_, err = downloadFileViaTempFile(client, path, "http://blabla", emptyHash)
+	_, err = downloadFileViaTempFile(context.Background(), client, NewDirOutput(path.Parent().Canonpath()), filepath.Base(path.Canonpath()), "http://blabla", emptyHash, AlgorithmSHA256)
 	assert.NoError(t, err)
 	assert.True(t, path.Exists(), "Downloaded file exists")
 	assert.NoError(t, path.Remove())
@@ -76,9 +80,9 @@ func TestDownloadFile(t *testing.T) {
 func TestDownloadWorker(t *testing.T) {
 	t.Run("File not found", func(t *testing.T) {
 		httpClient := func() httpClient { return &clientMock{statusCode: 404, status: "Not found"} }
-		downloadWorkersTest(t, StorClientOpts{}, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
-			assert.Equal(t, DOWN_FAIL, stat[0].Status)
-			assert.Equal(t, int64(0), stat[0].Size)
+		downloadWorkersTest(t, StorClientOpts{}, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, total TotalStat) {
+			assert.Equal(t, 0, total.Count)
+			assert.False(t, total.Status())
 		})
 	})
 
@@ -89,7 +93,7 @@ func TestDownloadWorker(t *testing.T) {
 
 	t.Run("uppercase", func(t *testing.T) {
 		httpClient := func() httpClient { return &clientMock{statusCode: 200, status: "Ok"} }
-		downloadWorkersTest(t, StorClientOpts{UpperCase: true}, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
+		downloadWorkersTest(t, StorClientOpts{UpperCase: true}, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, total TotalStat) {
 			downloadFile, err := tempdir.Child(strings.ToUpper(emptyHash.String()))
 			assert.NoError(t, err)
 
@@ -97,16 +101,16 @@ func TestDownloadWorker(t *testing.T) {
 				t.Log(tempdir.Children())
 			}
 
-			assert.Equal(t, DOWN_OK, stat[0].Status)
-			assert.Equal(t, int64(0), stat[0].Size)
+			assert.Equal(t, 1, total.Count)
+			assert.Equal(t, int64(0), total.Size)
 		})
 	})
 
 	t.Run("extension", func(t *testing.T) {
 		httpClient := func() httpClient { return &clientMock{statusCode: 200, status: "Ok"} }
-		downloadWorkersTest(t, StorClientOpts{UpperCase: true, Suffix: ".dat"}, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
-			assert.Equal(t, DOWN_OK, stat[0].Status)
-			assert.Equal(t, int64(0), stat[0].Size)
+		downloadWorkersTest(t, StorClientOpts{UpperCase: true, Suffix: ".dat"}, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, total TotalStat) {
+			assert.Equal(t, 1, total.Count)
+			assert.Equal(t, int64(0), total.Size)
 
 			downloadFile, err := tempdir.Child(strings.ToUpper(emptyHash.String()) + ".dat")
 			assert.NoError(t, err)
@@ -119,9 +123,9 @@ func TestDownloadWorker(t *testing.T) {
 
 	t.Run("more workers", func(t *testing.T) {
 		httpClient := func() httpClient { return &clientMockWithDelay{statusCode: 200, status: "Ok"} }
-		downloadWorkersTest(t, StorClientOpts{}, httpClient, []hashutil.Hash{emptyHash, emptyHash}, 2, func(tempdir pathutil.Path, stats []DownStat) {
-			assert.Equal(t, DOWN_SKIP, stats[0].Status)
-			assert.Equal(t, DOWN_OK, stats[1].Status)
+		downloadWorkersTest(t, StorClientOpts{}, httpClient, []hashutil.Hash{emptyHash, emptyHash}, 2, func(tempdir pathutil.Path, total TotalStat) {
+			assert.Equal(t, 1, total.Skip)
+			assert.Equal(t, 1, total.Count)
 
 			downloadFile, err := tempdir.Child(emptyHash.String())
 			assert.NoError(t, err)
@@ -136,9 +140,9 @@ func TestDownloadWorker(t *testing.T) {
 		header := http.Header{}
 		header.Add("Last-Modified", "Tue, 20 Mar 2018 15:48:42 GMT")
 		httpClient := func() httpClient { return &clientMock{statusCode: 200, status: "Ok", header: header} }
-		downloadWorkersTest(t, StorClientOpts{}, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
-			assert.Equal(t, DOWN_OK, stat[0].Status)
-			assert.Equal(t, int64(0), stat[0].Size)
+		downloadWorkersTest(t, StorClientOpts{}, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, total TotalStat) {
+			assert.Equal(t, 1, total.Count)
+			assert.Equal(t, int64(0), total.Size)
 
 			downloadFile, err := tempdir.Child(strings.ToLower(emptyHash.String()))
 			assert.NoError(t, err)
@@ -155,6 +159,87 @@ func TestDownloadWorker(t *testing.T) {
 		})
 	})
 
+	t.Run("path template", func(t *testing.T) {
+		httpClient := func() httpClient { return &clientMock{statusCode: 200, status: "Ok"} }
+		opts := StorClientOpts{PathTemplate: "{{.FirstShaByte}}/{{.SecondShaByte}}/{{.UpperSha}}", Suffix: ".dat"}
+		downloadWorkersTest(t, opts, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, total TotalStat) {
+			assert.Equal(t, 1, total.Count)
+
+			downloadFile, err := tempdir.Child("e3", "b0", strings.ToUpper(emptyHash.String())+".dat")
+			assert.NoError(t, err)
+
+			if !assert.True(t, downloadFile.Exists()) {
+				t.Log(tempdir.Children())
+			}
+		})
+	})
+
+	t.Run("zip and safe output with sharded path template", func(t *testing.T) {
+		outputs := map[string]func(dir string) (Output, error){
+			"zip": func(dir string) (Output, error) { return NewZipDirOutput(dir, DefaultZipPassword), nil },
+			"wrap": func(dir string) (Output, error) {
+				return NewSafeDirOutput(dir, SafeOpts{Wrapping: WrapXOR, Key: []byte("secret")})
+			},
+		}
+
+		for name, newOutput := range outputs {
+			t.Run(name, func(t *testing.T) {
+				outputDir, err := pathutil.NewTempDir(pathutil.TempOpt{})
+				assert.NoError(t, err)
+				defer func() {
+					assert.NoError(t, outputDir.RemoveTree())
+				}()
+
+				output, err := newOutput(outputDir.Canonpath())
+				assert.NoError(t, err)
+
+				httpClient := func() httpClient { return &clientMock{statusCode: 200, status: "Ok"} }
+				opts := StorClientOpts{Output: output, PathTemplate: "{{slice .Sha 0 2}}/{{.Sha}}"}
+				downloadWorkersTest(t, opts, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, total TotalStat) {
+					assert.Equal(t, 1, total.Count)
+					assert.Equal(t, 0, total.Fail)
+				})
+
+				stored := emptyHash.String()
+				if name == "zip" {
+					stored += ".zip"
+				}
+				downloadFile, err := outputDir.Child("e3", stored)
+				assert.NoError(t, err)
+				if !assert.True(t, downloadFile.Exists()) {
+					t.Log(outputDir.Children())
+				}
+
+				shard, err := outputDir.Child("e3")
+				assert.NoError(t, err)
+				children, err := shard.Children()
+				assert.NoError(t, err)
+				assert.Len(t, children, 1, "temporary files are removed")
+			})
+		}
+	})
+
+	t.Run("tar output", func(t *testing.T) {
+		var archive bytes.Buffer
+		output, err := NewTarOutput(&archive, CompressionNone)
+		assert.NoError(t, err)
+
+		httpClient := func() httpClient { return &clientMock{statusCode: 200, status: "Ok"} }
+		downloadWorkersTest(t, StorClientOpts{Output: output, Suffix: ".dat"}, httpClient, []hashutil.Hash{emptyHash, emptyHash}, 1, func(tempdir pathutil.Path, total TotalStat) {
+			assert.Equal(t, 1, total.Count)
+			assert.Equal(t, 1, total.Skip)
+
+			children, err := tempdir.Children()
+			assert.NoError(t, err)
+			assert.Empty(t, children)
+		})
+		assert.NoError(t, output.Close())
+
+		header, err := tar.NewReader(&archive).Next()
+		assert.NoError(t, err)
+		assert.Equal(t, emptyHash.String()+".dat", header.Name)
+	})
+
 	t.Run("S3 first download ok", func(t *testing.T) {
 		httpClient := func() httpClient { return &clientMock{statusCode: 200, status: "Ok"} }
 		downloadWorkersTestDownloadOK(t, StorClientOpts{S3URL: &url.URL{}}, httpClient, []hashutil.Hash{emptyHash}, 1)
@@ -176,42 +261,77 @@ func TestDownloadWorker(t *testing.T) {
 	})
 }
 
-func downloadWorkersTest(t *testing.T, storClientOpts StorClientOpts, httpClientFunc func() httpClient, sha256list []hashutil.Hash, workers int, asserts func(pathutil.Path, []DownStat)) {
+func TestCreateFilePath(t *testing.T) {
+	object := Object{Sha: emptyHash, Name: "sample.exe"}
+
+	for template, expected := range map[string]string{
+		"": emptyHash.String() + ".dat",
+		"{{.FirstShaByte}}/{{.SecondShaByte}}/{{.Sha}}": "e3/b0/" + emptyHash.String() + ".dat",
+		"{{.Name}}":         "sample.exe.dat",
+		"files/./{{.Name}}": "files/sample.exe.dat",
+	} {
+		client, err := New(url.URL{}, "", StorClientOpts{PathTemplate: template, Suffix: ".dat"})
+		assert.NoError(t, err)
+
+		path, err := client.createFilePath(object)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, path)
+	}
+
+	client, err := New(url.URL{}, "", StorClientOpts{UpperCase: true})
+	assert.NoError(t, err)
+	path, err := client.createFilePath(object)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.ToUpper(emptyHash.String()), path)
+
+	path, err = client.createFilePath(Object{Sha: emptyHash, Dir: "incident/42"})
+	assert.NoError(t, err)
+	assert.Equal(t, "incident/42/"+strings.ToUpper(emptyHash.String()), path)
+
+	_, err = client.createFilePath(Object{Sha: emptyHash, Dir: "../.."})
+	assert.Error(t, err)
+
+	for _, name := range []string{"", "../../etc/passwd", "/etc/passwd", "a/../.."} {
+		client, err := New(url.URL{}, "", StorClientOpts{PathTemplate: "{{.Name}}"})
+		assert.NoError(t, err)
+
+		_, err = client.createFilePath(Object{Sha: emptyHash, Name: name})
+		assert.Error(t, err, name)
+	}
+}
+
+func downloadWorkersTest(t *testing.T, storClientOpts StorClientOpts, httpClientFunc func() httpClient, sha256list []hashutil.Hash, workers int, asserts func(pathutil.Path, TotalStat)) {
 	tempdir, err := pathutil.NewTempDir(pathutil.TempOpt{})
 	assert.NoError(t, err)
 	defer func() {
 		assert.NoError(t, tempdir.RemoveTree())
 	}()
+	storClientOpts.Max = workers
 	storClient, err := New(url.URL{}, tempdir.Canonpath(), storClientOpts)
 	assert.NoError(t, err)
 
-	storClient.wg.Add(workers)
 	log.SetLevel(log.DebugLevel)
 
-	shasForDownload := make(chan hashutil.Hash, 3)
-	downloadedFilesStat := make(chan DownStat, 3)
-
-	for _, sha256 := range sha256list {
-		shasForDownload <- sha256
-	}
-
-	shasForDownload <- workerEnd
-
 	for i := 0; i < workers; i++ {
-		go storClient.downloadWorker(0, httpClientFunc, shasForDownload, downloadedFilesStat)
+		storClient.wg.Add(1)
+		go storClient.downloadWorker(i, httpClientFunc, storClient.pool.input)
 	}
+	defer func() {
+		assert.NoError(t, storClient.Close())
+	}()
 
-	stats := make([]DownStat, workers)
-	for i := 0; i < workers; i++ {
-		stats[i] = <-downloadedFilesStat
+	batch := storClient.NewBatch()
+	for _, sha256 := range sha256list {
+		batch.Download(sha256)
 	}
-	asserts(tempdir, stats)
+
+	asserts(tempdir, batch.Wait())
 }
 
 func downloadWorkersTestDownloadOK(t *testing.T, storClientOpts StorClientOpts, httpClientFunc func() httpClient, sha256list []hashutil.Hash, workers int) {
-	downloadWorkersTest(t, storClientOpts, httpClientFunc, sha256list, workers, func(tempdir pathutil.Path, stat []DownStat) {
-		assert.Equal(t, DOWN_OK, stat[0].Status)
-		assert.Equal(t, int64(0), stat[0].Size)
+	downloadWorkersTest(t, storClientOpts, httpClientFunc, sha256list, workers, func(tempdir pathutil.Path, total TotalStat) {
+		assert.Equal(t, 1, total.Count)
+		assert.Equal(t, int64(0), total.Size)
 
 		downloadFile, err := tempdir.Child(strings.ToLower(emptyHash.String()))
 		assert.NoError(t, err)
@@ -0,0 +1,165 @@
+package storclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/retry-go"
+)
+
+// errors of public API can be matched by errors.Is and errors.As, e.g.
+//
+//	reader, err := client.Get(ctx, sha)
+//	if errors.Is(err, storclient.ErrNotFound) {
+//		...
+//	}
+//
+//	var statusErr *storclient.HTTPStatusError
+//	if errors.As(err, &statusErr) {
+//		log.Printf("%s returns %d", statusErr.Backend, statusErr.StatusCode)
+//	}
+var (
+	// ErrNotFound - object isn't on storage (404 of stor)
+	ErrNotFound = errors.New("object not found")
+	// ErrHashMismatch - hash of content doesn't match hash of object
+	ErrHashMismatch = errors.New("hash mismatch")
+	// ErrCancelled - context is cancelled (or its deadline is exceeded)
+	ErrCancelled = errors.New("cancelled")
+)
+
+// HTTPStatusError is unexpected status code of response of storage (or mapping service of HashResolver)
+//
+// 404 matches ErrNotFound
+type HTTPStatusError struct {
+	// sha of object (empty for mapping service)
+	Sha hashutil.Hash
+	// method of request (GET, HEAD, PUT)
+	Method string
+	URL    string
+	// backend (s3, stor, resolver) of request
+	Backend    string
+	StatusCode int
+	Status     string
+}
+
+func (err *HTTPStatusError) Error() string {
+	if err.Backend == backendResolver {
+		return fmt.Sprintf("Mapping service %s return status code %d (%s)", err.URL, err.StatusCode, err.Status)
+	}
+
+	operation := "Download"
+	if err.Method == http.MethodPut {
+		operation = "Upload"
+	}
+
+	return fmt.Sprintf("%s of %s fail %d (%s)", operation, err.Sha, err.StatusCode, err.Status)
+}
+
+// Is returns true for ErrNotFound if status code is 404
+func (err *HTTPStatusError) Is(target error) bool {
+	return target == ErrNotFound && err.StatusCode == http.StatusNotFound
+}
+
+// hashMismatchError is failed verification of content, matches ErrHashMismatch
+type hashMismatchError struct {
+	expected, downloaded hashutil.Hash
+}
+
+func (err hashMismatchError) Error() string {
+	return fmt.Sprintf("Downloaded sha (%s) is not equal with expected sha (%s)", err.downloaded, err.expected)
+}
+
+func (err hashMismatchError) Is(target error) bool {
+	return target == ErrHashMismatch
+}
+
+// cancelError is error of request (or retry) stopped by cancelled context, matches ErrCancelled
+// and unwraps to underlying error (e.g. context.Canceled)
+type cancelError struct {
+	err error
+}
+
+func (err cancelError) Error() string {
+	return fmt.Sprintf("Cancelled: %s", err.err)
+}
+
+func (err cancelError) Is(target error) bool {
+	return target == ErrCancelled
+}
+
+func (err cancelError) Unwrap() error {
+	return err.err
+}
+
+// attemptsError is error of all attempts of retry (message of retry.Error),
+// it unwraps to error of last attempt
+type attemptsError struct {
+	errs retry.Error
+}
+
+func (err attemptsError) Error() string {
+	return err.errs.Error()
+}
+
+func (err attemptsError) Unwrap() error {
+	for i := len(err.errs) - 1; i >= 0; i-- {
+		if err.errs[i] != nil {
+			return err.errs[i]
+		}
+	}
+
+	return nil
+}
+
+// retryResult converts error of retry.Do to error of public API
+// (cancelError if ctx is cancelled, attemptsError otherwise)
+func retryResult(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if retryErr, ok := err.(retry.Error); ok {
+		err = attemptsError{retryErr}
+	}
+
+	if ctx.Err() != nil {
+		return cancelError{err: err}
+	}
+
+	return err
+}
+
+// isNetworkError returns true if err is error of connection or interrupted transfer
+//
+// errno of file system (e.g. wrapped by os.PathError) implements net.Error too, but it isn't network error
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		_, isErrno := netErr.(syscall.Errno)
+		return !isErrno
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isAccessDenied returns true if err is HTTPStatusError of 403 Forbidden
+func isAccessDenied(err error) bool {
+	var statusErr *HTTPStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusForbidden
+}
+
+// isServerError returns true if err is HTTPStatusError of server error (5xx) or throttling (429)
+func isServerError(err error) bool {
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+
+	return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+}
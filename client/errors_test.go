@@ -0,0 +1,89 @@
+package storclient_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors(t *testing.T) {
+	missingSha, err := hashutil.StringToHash(sha256.New(), "01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b")
+	assert.NoError(t, err)
+	brokenSha, err := hashutil.StringToHash(sha256.New(), "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	assert.NoError(t, err)
+
+	stor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut:
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == fmt.Sprintf("/%s", brokenSha):
+			fmt.Fprint(w, "bye")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer stor.Close()
+
+	storURL, _ := url.Parse(stor.URL)
+	client, err := storclient.New(*storURL, "some_dir", storclient.StorClientOpts{RetryAttempts: 2, RetryDelay: time.Millisecond})
+	assert.NoError(t, err)
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := client.Get(context.Background(), missingSha)
+		assert.True(t, errors.Is(err, storclient.ErrNotFound))
+		assert.False(t, errors.Is(err, storclient.ErrHashMismatch))
+
+		var statusErr *storclient.HTTPStatusError
+		if assert.True(t, errors.As(err, &statusErr)) {
+			assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+			assert.Equal(t, "stor", statusErr.Backend)
+			assert.Equal(t, http.MethodGet, statusErr.Method)
+			assert.Equal(t, fmt.Sprintf("%s/%s", stor.URL, missingSha), statusErr.URL)
+		}
+
+		_, err = client.Stat(context.Background(), missingSha)
+		assert.True(t, errors.Is(err, storclient.ErrNotFound), "stat")
+	})
+
+	t.Run("hash mismatch", func(t *testing.T) {
+		reader, err := client.Get(context.Background(), brokenSha)
+		assert.NoError(t, err)
+		defer reader.Close()
+
+		_, err = ioutil.ReadAll(reader)
+		assert.True(t, errors.Is(err, storclient.ErrHashMismatch))
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := client.Get(ctx, missingSha)
+		assert.True(t, errors.Is(err, storclient.ErrCancelled))
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.False(t, errors.Is(err, storclient.ErrNotFound))
+	})
+
+	t.Run("upload", func(t *testing.T) {
+		err := client.Put(context.Background(), missingSha, bytes.NewReader([]byte("hello")))
+
+		var statusErr *storclient.HTTPStatusError
+		if assert.True(t, errors.As(err, &statusErr)) {
+			assert.Equal(t, http.StatusForbidden, statusErr.StatusCode)
+			assert.Equal(t, http.MethodPut, statusErr.Method)
+		}
+		assert.Contains(t, err.Error(), "Upload of")
+	})
+}
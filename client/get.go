@@ -0,0 +1,58 @@
+package storclient
+
+import (
+	"context"
+	"hash"
+	"io"
+	"net/http"
+
+	"github.com/avast/hashutil-go"
+)
+
+type verifyReader struct {
+	body        io.ReadCloser
+	hasher      hash.Hash
+	expectedSha hashutil.Hash
+}
+
+// Get returns reader of object content
+//
+// object is fetched same way as in Download (S3 first if is set, stor fallback, retry),
+// but nothing is written to disk
+//
+// hash (Algorithm) of content is verified at EOF, mismatch is returned as error of Read (instead of io.EOF)
+//
+// reader must be closed by caller
+func (client *StorClient) Get(ctx context.Context, sha hashutil.Hash) (io.ReadCloser, error) {
+	var resp *http.Response
+	_, err := client.retryWithFallback(ctx, Fields{fieldSha: sha.String()}, Object{Sha: sha}, nil, func(ctx context.Context, u string) error {
+		var err error
+		resp, err = getObject(ctx, client.httpClient, u, sha)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &verifyReader{body: resp.Body, hasher: client.Algorithm.New(), expectedSha: sha}, nil
+}
+
+func (r *verifyReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if _, errHash := r.hasher.Write(p[:n]); errHash != nil {
+		return n, errHash
+	}
+
+	if err == io.EOF {
+		if errVerify := verifySha(r.hasher, r.expectedSha); errVerify != nil {
+			return n, errVerify
+		}
+	}
+
+	return n, err
+}
+
+func (r *verifyReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,80 @@
+package storclient_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	content := []byte("sample")
+	hasher := sha256.New()
+	_, _ = hasher.Write(content)
+	contentSha, err := hashutil.BytesToHash(sha256.New(), hasher.Sum(nil))
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == fmt.Sprintf("/%s", contentSha) {
+			_, _ = w.Write(content)
+			return
+		}
+
+		if r.URL.Path == fmt.Sprintf("/%s", emptySha) {
+			_, _ = w.Write([]byte("corrupted"))
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "some_dir", storclient.StorClientOpts{RetryAttempts: 2})
+	assert.NoError(t, err)
+
+	t.Run("ok", func(t *testing.T) {
+		reader, err := client.Get(context.Background(), contentSha)
+		assert.NoError(t, err)
+
+		got, err := ioutil.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, content, got)
+		assert.NoError(t, reader.Close())
+	})
+
+	t.Run("sha mismatch", func(t *testing.T) {
+		reader, err := client.Get(context.Background(), emptySha)
+		assert.NoError(t, err)
+
+		_, err = ioutil.ReadAll(reader)
+		assert.Error(t, err)
+		assert.NoError(t, reader.Close())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		sha, err := hashutil.StringToHash(sha256.New(), "01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b")
+		assert.NoError(t, err)
+
+		_, err = client.Get(context.Background(), sha)
+		assert.Error(t, err)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := client.Get(ctx, contentSha)
+		assert.Error(t, err)
+	})
+}
+
+var emptySha = hashutil.EmptyHash(sha256.New())
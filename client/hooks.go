@@ -0,0 +1,127 @@
+package storclient
+
+import (
+	"io"
+	"net/http"
+)
+
+// Hooks are callbacks of lifecycle of downloads (see StorClientOpts.Hooks), e.g. for own dashboards or audit logs
+//
+// hooks are called from download workers (concurrently for different objects),
+// so they must be safe for concurrent use and should return quickly; nil hook is skipped
+//
+// lifecycle of object is OnQueued, OnStart, then OnAttempt (with OnProgress during transfer)
+// followed by OnRetry or OnBackendFallback for every repeated attempt and finally OnComplete (downloaded or failed)
+// or OnSkip (without attempts)
+type Hooks struct {
+	// object is added to download queue
+	OnQueued func(object Object)
+	// worker starts processing of object
+	OnStart func(object Object, worker int)
+	// attempt (counted from 1) of request of object from backend (s3, stor) with url
+	OnAttempt func(object Object, attempt int, backend string, url string)
+	// attempt fails and object will be requested again
+	OnRetry func(object Object, attempt int, err error)
+	// object isn't on backend from (s3) and it will be requested from backend to (stor)
+	OnBackendFallback func(object Object, from string, to string)
+	// bytes of content of object are received (count since previous call)
+	OnProgress func(object Object, bytes int64)
+	// download of object is finished (DOWN_OK or DOWN_FAIL)
+	OnComplete func(stat DownStat)
+	// object is skipped (file exists or object is downloading by other worker)
+	OnSkip func(stat DownStat)
+}
+
+// methods are noop on nil hooks (client without hooks)
+
+func (hooks *Hooks) queued(object Object) {
+	if hooks != nil && hooks.OnQueued != nil {
+		hooks.OnQueued(object)
+	}
+}
+
+func (hooks *Hooks) start(object Object, worker int) {
+	if hooks != nil && hooks.OnStart != nil {
+		hooks.OnStart(object, worker)
+	}
+}
+
+func (hooks *Hooks) attempt(object Object, attempt int, backend string, url string) {
+	if hooks != nil && hooks.OnAttempt != nil {
+		hooks.OnAttempt(object, attempt, backend, url)
+	}
+}
+
+func (hooks *Hooks) retry(object Object, attempt int, err error) {
+	if hooks != nil && hooks.OnRetry != nil {
+		hooks.OnRetry(object, attempt, err)
+	}
+}
+
+func (hooks *Hooks) fallback(object Object, from string, to string) {
+	if hooks != nil && hooks.OnBackendFallback != nil {
+		hooks.OnBackendFallback(object, from, to)
+	}
+}
+
+func (hooks *Hooks) finish(stat DownStat) {
+	if hooks == nil {
+		return
+	}
+
+	if stat.Status == DOWN_SKIP {
+		if hooks.OnSkip != nil {
+			hooks.OnSkip(stat)
+		}
+		return
+	}
+
+	if hooks.OnComplete != nil {
+		hooks.OnComplete(stat)
+	}
+}
+
+// progressHTTPClient calls progress with count of read bytes of response bodies
+type progressHTTPClient struct {
+	httpClient httpClient
+	progress   func(bytes int64)
+}
+
+// withProgress returns httpClient reporting progress of object to OnProgress hook (httpClient if hook isn't set)
+func (hooks *Hooks) withProgress(httpClient httpClient, object Object) httpClient {
+	if hooks == nil || hooks.OnProgress == nil {
+		return httpClient
+	}
+
+	return progressHTTPClient{
+		httpClient: httpClient,
+		progress: func(bytes int64) {
+			hooks.OnProgress(object, bytes)
+		},
+	}
+}
+
+func (c progressHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = progressReader{ReadCloser: resp.Body, progress: c.progress}
+
+	return resp, nil
+}
+
+type progressReader struct {
+	io.ReadCloser
+	progress func(bytes int64)
+}
+
+func (r progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.progress(int64(n))
+	}
+
+	return n, err
+}
@@ -0,0 +1,118 @@
+package storclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks(t *testing.T) {
+	helloSha := testHash(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer s3.Close()
+
+	failures := 0
+	stor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures == 0 {
+			failures++
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprint(w, "hello")
+	}))
+	defer stor.Close()
+
+	tempdir, err := ioutil.TempDir("", "hooks")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	var lock sync.Mutex
+	var events []string
+	var received int64
+	event := func(format string, args ...interface{}) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, fmt.Sprintf(format, args...))
+	}
+
+	hooks := &Hooks{
+		OnQueued: func(object Object) { event("queued %s", object.Name) },
+		OnStart:  func(object Object, worker int) { event("start %s %d", object.Name, worker) },
+		OnAttempt: func(object Object, attempt int, backend string, url string) {
+			event("attempt %s %d %s", object.Name, attempt, backend)
+		},
+		OnRetry: func(object Object, attempt int, err error) {
+			event("retry %s %d %s", object.Name, attempt, err)
+		},
+		OnBackendFallback: func(object Object, from string, to string) {
+			event("fallback %s %s->%s", object.Name, from, to)
+		},
+		OnProgress: func(object Object, bytes int64) {
+			lock.Lock()
+			defer lock.Unlock()
+			received += bytes
+		},
+		OnComplete: func(stat DownStat) { event("complete %s %s", stat.Object.Name, stat.Status) },
+		OnSkip:     func(stat DownStat) { event("skip %s", stat.Object.Name) },
+	}
+
+	storURL, _ := url.Parse(stor.URL)
+	s3URL, _ := url.Parse(s3.URL)
+	client, err := New(*storURL, tempdir, StorClientOpts{Max: 1, RetryDelay: time.Millisecond, S3URL: s3URL, Hooks: hooks})
+	assert.NoError(t, err)
+
+	client.Start()
+	defer func() {
+		assert.NoError(t, client.Close())
+	}()
+
+	client.DownloadObject(Object{Sha: helloSha, Name: "a"})
+	assert.True(t, client.Wait().Status())
+
+	client.DownloadObject(Object{Sha: helloSha, Name: "b"})
+	assert.True(t, client.Wait().Status())
+
+	assert.Equal(t, []string{
+		"queued a",
+		"start a 0",
+		"attempt a 1 s3",
+		"fallback a s3->stor",
+		"attempt a 2 stor",
+		"retry a 2 " + fmt.Sprintf("Download of %s fail 503 (503 Service Unavailable)", helloSha),
+		"attempt a 3 stor",
+		"complete a ok",
+		"queued b",
+		"start b 0",
+		"skip b",
+	}, events)
+	assert.Equal(t, int64(len("hello")), received)
+}
+
+func TestNilHooks(t *testing.T) {
+	var hooks *Hooks
+
+	hooks.queued(Object{})
+	hooks.start(Object{}, 1)
+	hooks.attempt(Object{}, 1, backendStor, "")
+	hooks.retry(Object{}, 1, nil)
+	hooks.fallback(Object{}, backendS3, backendStor)
+	hooks.finish(DownStat{})
+
+	httpClient := &http.Client{}
+	assert.Equal(t, httpClient, hooks.withProgress(httpClient, Object{}))
+	assert.Equal(t, httpClient, (&Hooks{}).withProgress(httpClient, Object{}))
+}
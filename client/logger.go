@@ -0,0 +1,79 @@
+package storclient
+
+import "github.com/sirupsen/logrus"
+
+// Logger is logger of client (see StorClientOpts.Logger)
+//
+// records of client have consistent fields: sha256, worker, backend (s3, stor) and attempt,
+// adapter of logrus is NewLogrusLogger, adapters of zap and slog are in subpackages logzap and logslog
+type Logger interface {
+	Debug(msg string, fields Fields)
+	Info(msg string, fields Fields)
+	Warn(msg string, fields Fields)
+	Error(msg string, fields Fields)
+}
+
+// Fields are structured fields of log record
+type Fields map[string]interface{}
+
+// names of fields of log records
+const (
+	fieldSha     = "sha256"
+	fieldWorker  = "worker"
+	fieldBackend = "backend"
+	fieldAttempt = "attempt"
+	fieldError   = "error"
+)
+
+// with returns copy of fields with added key
+func (fields Fields) with(key string, value interface{}) Fields {
+	copied := make(Fields, len(fields)+1)
+	for k, v := range fields {
+		copied[k] = v
+	}
+	copied[key] = value
+
+	return copied
+}
+
+// defaultLogger is logger of client without StorClientOpts.Logger
+func defaultLogger() Logger {
+	return NewLogrusLogger(logrus.StandardLogger())
+}
+
+type logrusLogger struct {
+	logger logrus.FieldLogger
+}
+
+// NewLogrusLogger returns Logger writing to logrus logger (e.g. logrus.StandardLogger() - default of client)
+func NewLogrusLogger(logger logrus.FieldLogger) Logger {
+	return logrusLogger{logger: logger}
+}
+
+func (l logrusLogger) Debug(msg string, fields Fields) {
+	l.logger.WithFields(logrus.Fields(fields)).Debug(msg)
+}
+
+func (l logrusLogger) Info(msg string, fields Fields) {
+	l.logger.WithFields(logrus.Fields(fields)).Info(msg)
+}
+
+func (l logrusLogger) Warn(msg string, fields Fields) {
+	l.logger.WithFields(logrus.Fields(fields)).Warn(msg)
+}
+
+func (l logrusLogger) Error(msg string, fields Fields) {
+	l.logger.WithFields(logrus.Fields(fields)).Error(msg)
+}
+
+type nopLogger struct{}
+
+// NewNopLogger returns Logger which discards all records (silent client)
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) Debug(string, Fields) {}
+func (nopLogger) Info(string, Fields)  {}
+func (nopLogger) Warn(string, Fields)  {}
+func (nopLogger) Error(string, Fields) {}
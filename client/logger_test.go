@@ -0,0 +1,93 @@
+package storclient
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRecord struct {
+	level, msg string
+	fields     Fields
+}
+
+type testLogger struct {
+	lock    sync.Mutex
+	records []testRecord
+}
+
+func (l *testLogger) log(level, msg string, fields Fields) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.records = append(l.records, testRecord{level: level, msg: msg, fields: fields})
+}
+
+func (l *testLogger) Debug(msg string, fields Fields) { l.log("debug", msg, fields) }
+func (l *testLogger) Info(msg string, fields Fields)  { l.log("info", msg, fields) }
+func (l *testLogger) Warn(msg string, fields Fields)  { l.log("warn", msg, fields) }
+func (l *testLogger) Error(msg string, fields Fields) { l.log("error", msg, fields) }
+
+func TestClientLogger(t *testing.T) {
+	lastModified := "Thu, 01 Mar 2018 10:00:00 GMT"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastModified)
+	}))
+	defer server.Close()
+
+	logger := &testLogger{}
+	storURL, _ := url.Parse(server.URL)
+	client, err := New(*storURL, "some_dir", StorClientOpts{Devnull: true, Max: 1, RetryDelay: time.Millisecond, Logger: logger})
+	assert.NoError(t, err)
+
+	client.Start()
+	client.Download(emptyHash)
+	assert.True(t, client.Wait().Status())
+	assert.NoError(t, client.Close())
+
+	var downloaded *testRecord
+	for i, record := range logger.records {
+		assert.NotContains(t, record.msg, lastModified, "Last-Modified isn't logged")
+		if record.msg == "Downloaded "+emptyHash.String() {
+			downloaded = &logger.records[i]
+		}
+	}
+
+	if assert.NotNil(t, downloaded) {
+		assert.Equal(t, "debug", downloaded.level)
+		assert.Equal(t, Fields{fieldWorker: 0, fieldSha: emptyHash.String(), fieldBackend: backendStor}, downloaded.fields)
+	}
+}
+
+func TestLoggerAdapters(t *testing.T) {
+	fields := Fields{fieldSha: "abc", fieldAttempt: 2}
+
+	t.Run("logrus", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := logrus.New()
+		logger.Out = &out
+		logger.Formatter = &logrus.TextFormatter{DisableTimestamp: true}
+		logger.Level = logrus.DebugLevel
+
+		NewLogrusLogger(logger).Warn("hello", fields)
+		assert.Equal(t, "level=warning msg=hello attempt=2 sha256=abc\n", out.String())
+	})
+
+	t.Run("nop", func(t *testing.T) {
+		NewNopLogger().Info("hello", fields)
+	})
+}
+
+func TestFieldsWith(t *testing.T) {
+	fields := Fields{fieldSha: "abc"}
+
+	assert.Equal(t, Fields{fieldSha: "abc", fieldAttempt: 1}, fields.with(fieldAttempt, 1))
+	assert.Equal(t, Fields{fieldSha: "abc"}, fields, "original fields aren't changed")
+}
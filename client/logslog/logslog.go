@@ -0,0 +1,53 @@
+// Package logslog is adapter of log/slog logger (Go 1.21+) to storclient.Logger
+//
+//	client, err := storclient.New(storURL, dir, storclient.StorClientOpts{Logger: logslog.New(slog.Default())})
+package logslog
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"github.com/avast/stor-client/client"
+)
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// New returns storclient.Logger writing to slog logger
+func New(logger *slog.Logger) storclient.Logger {
+	return slogLogger{logger: logger}
+}
+
+// log writes record with fields in stable order (sorted by key)
+func (l slogLogger) log(level slog.Level, msg string, fields storclient.Fields) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, key := range keys {
+		attrs = append(attrs, slog.Any(key, fields[key]))
+	}
+
+	l.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+func (l slogLogger) Debug(msg string, fields storclient.Fields) {
+	l.log(slog.LevelDebug, msg, fields)
+}
+
+func (l slogLogger) Info(msg string, fields storclient.Fields) {
+	l.log(slog.LevelInfo, msg, fields)
+}
+
+func (l slogLogger) Warn(msg string, fields storclient.Fields) {
+	l.log(slog.LevelWarn, msg, fields)
+}
+
+func (l slogLogger) Error(msg string, fields storclient.Fields) {
+	l.log(slog.LevelError, msg, fields)
+}
@@ -0,0 +1,26 @@
+package logslog
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	New(logger).Debug("hello", storclient.Fields{"sha256": "abc", "attempt": 2})
+	assert.Equal(t, "level=DEBUG msg=hello attempt=2 sha256=abc\n", out.String())
+}
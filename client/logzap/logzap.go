@@ -0,0 +1,52 @@
+// Package logzap is adapter of zap logger to storclient.Logger
+//
+//	client, err := storclient.New(storURL, dir, storclient.StorClientOpts{Logger: logzap.New(zapLogger)})
+package logzap
+
+import (
+	"sort"
+
+	"github.com/avast/stor-client/client"
+	"go.uber.org/zap"
+)
+
+type zapLogger struct {
+	logger *zap.Logger
+}
+
+// New returns storclient.Logger writing to zap logger
+func New(logger *zap.Logger) storclient.Logger {
+	return zapLogger{logger: logger}
+}
+
+// zapFields returns fields in stable order (sorted by key)
+func (l zapLogger) zapFields(fields storclient.Fields) []zap.Field {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	zapFields := make([]zap.Field, 0, len(fields))
+	for _, key := range keys {
+		zapFields = append(zapFields, zap.Any(key, fields[key]))
+	}
+
+	return zapFields
+}
+
+func (l zapLogger) Debug(msg string, fields storclient.Fields) {
+	l.logger.Debug(msg, l.zapFields(fields)...)
+}
+
+func (l zapLogger) Info(msg string, fields storclient.Fields) {
+	l.logger.Info(msg, l.zapFields(fields)...)
+}
+
+func (l zapLogger) Warn(msg string, fields storclient.Fields) {
+	l.logger.Warn(msg, l.zapFields(fields)...)
+}
+
+func (l zapLogger) Error(msg string, fields storclient.Fields) {
+	l.logger.Error(msg, l.zapFields(fields)...)
+}
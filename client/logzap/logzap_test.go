@@ -0,0 +1,20 @@
+package logzap
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogger(t *testing.T) {
+	var out bytes.Buffer
+	encoder := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{MessageKey: "msg", LevelKey: "level", EncodeLevel: zapcore.LowercaseLevelEncoder})
+	logger := zap.New(zapcore.NewCore(encoder, zapcore.AddSync(&out), zapcore.DebugLevel))
+
+	New(logger).Error("hello", storclient.Fields{"sha256": "abc", "attempt": 2})
+	assert.Equal(t, "error\thello\t{\"attempt\": 2, \"sha256\": \"abc\"}\n", out.String())
+}
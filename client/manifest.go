@@ -0,0 +1,77 @@
+package storclient
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Manifest writes record (ManifestEntry) about every processed object as JSON line
+type Manifest struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// ManifestEntry is one record of manifest
+//
+// Sha256 is hash of Algorithm if Algorithm is set (other than sha256)
+type ManifestEntry struct {
+	Sha256    string   `json:"sha256"`
+	Algorithm string   `json:"algorithm,omitempty"`
+	Origin    string   `json:"origin,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Dir       string   `json:"dir,omitempty"`
+	Priority  int      `json:"priority,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Version   string   `json:"version,omitempty"`
+	Path      string   `json:"path,omitempty"`
+	Status    string   `json:"status"`
+	Size      int64    `json:"size"`
+	Duration  float64  `json:"duration"`
+	Error     string   `json:"error,omitempty"`
+	// exit code of post-download command (--exec of cli), nil if command isn't run
+	ExitCode *int `json:"exit_code,omitempty"`
+	// file is deleted after successful post-download command
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// NewManifest returns manifest writing to w
+func NewManifest(w io.Writer) *Manifest {
+	return &Manifest{encoder: json.NewEncoder(w)}
+}
+
+// NewManifestEntry returns manifest record of download stat
+func NewManifestEntry(stat DownStat) ManifestEntry {
+	entry := ManifestEntry{
+		Sha256:   stat.Object.Sha.String(),
+		Origin:   stat.Object.Origin,
+		Name:     stat.Object.Name,
+		Dir:      stat.Object.Dir,
+		Priority: stat.Object.Priority,
+		Tags:     stat.Object.Tags,
+		Version:  stat.Object.Version,
+		Path:     stat.Path,
+		Status:   stat.Status.String(),
+		Size:     stat.Size,
+		Duration: stat.Duration.Seconds(),
+	}
+
+	if stat.Error != nil {
+		entry.Error = stat.Error.Error()
+	}
+
+	return entry
+}
+
+// Write writes record about download stat to manifest
+func (m *Manifest) Write(stat DownStat) error {
+	return m.WriteEntry(NewManifestEntry(stat))
+}
+
+// WriteEntry writes record to manifest
+func (m *Manifest) WriteEntry(entry ManifestEntry) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.encoder.Encode(entry)
+}
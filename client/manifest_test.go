@@ -0,0 +1,51 @@
+package storclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifest(t *testing.T) {
+	var out bytes.Buffer
+	manifest := NewManifest(&out)
+
+	assert.NoError(t, manifest.Write(DownStat{
+		Status:   DOWN_OK,
+		Size:     10,
+		Duration: time.Second,
+		Object:   Object{Sha: emptyHash, Name: "a.exe", Dir: "x", Priority: 2, Tags: []string{"pe"}, Origin: "md5:d41d8cd98f00b204e9800998ecf8427e"},
+		Path:     "x/a.exe",
+	}))
+	assert.NoError(t, manifest.Write(DownStat{
+		Status: DOWN_FAIL,
+		Object: Object{Sha: emptyHash},
+		Error:  errors.New("not found"),
+	}))
+
+	decoder := json.NewDecoder(&out)
+
+	var entry ManifestEntry
+	assert.NoError(t, decoder.Decode(&entry))
+	assert.Equal(t, ManifestEntry{
+		Sha256:   emptyHash.String(),
+		Origin:   "md5:d41d8cd98f00b204e9800998ecf8427e",
+		Name:     "a.exe",
+		Dir:      "x",
+		Priority: 2,
+		Tags:     []string{"pe"},
+		Path:     "x/a.exe",
+		Status:   "ok",
+		Size:     10,
+		Duration: 1,
+	}, entry)
+
+	entry = ManifestEntry{}
+	assert.NoError(t, decoder.Decode(&entry))
+	assert.Equal(t, "fail", entry.Status)
+	assert.Equal(t, "not found", entry.Error)
+}
@@ -0,0 +1,156 @@
+package storclient
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// namespace of prometheus metrics
+const metricsNamespace = "stor_client"
+
+// backends of objects (label backend of metrics)
+const (
+	backendS3   = "s3"
+	backendStor = "stor"
+	// object isn't requested (skipped or failed before request)
+	backendNone = "none"
+)
+
+// Metrics are prometheus metrics of client (see StorClientOpts.Metrics)
+//
+// metrics can be shared by more clients, e.g.
+//
+//	metrics, err := storclient.NewMetrics(prometheus.DefaultRegisterer)
+//	if err != nil {
+//		return err
+//	}
+//
+//	client, err := storclient.New(storageUrl, downloadDir, storclient.StorClientOpts{Metrics: metrics})
+type Metrics struct {
+	downloads        *prometheus.CounterVec
+	bytes            *prometheus.CounterVec
+	retries          *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	downloadDuration *prometheus.HistogramVec
+	queued           prometheus.Gauge
+	inFlight         prometheus.Gauge
+}
+
+// NewMetrics creates metrics and registers them to registerer (e.g. prometheus.DefaultRegisterer)
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	metrics := &Metrics{
+		downloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "downloads_total",
+			Help:      "Count of processed objects by status (ok, skip, fail) and backend (s3, stor, none).",
+		}, []string{"status", "backend"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "downloaded_bytes_total",
+			Help:      "Size of downloaded objects by backend.",
+		}, []string{"backend"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "retries_total",
+			Help:      "Count of repeated attempts (all attempts except first) by backend of attempt.",
+		}, []string{"backend"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of one attempt (request and transfer of content) by backend.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"backend"}),
+		downloadDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "download_duration_seconds",
+			Help:      "Duration of successful download of object including retries by backend.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"backend"}),
+		queued: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "queue_depth",
+			Help:      "Count of objects waiting for free download worker.",
+		}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "in_flight",
+			Help:      "Count of objects which are downloading now.",
+		}),
+	}
+
+	for _, collector := range metrics.collectors() {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return metrics, nil
+}
+
+func (metrics *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		metrics.downloads,
+		metrics.bytes,
+		metrics.retries,
+		metrics.requestDuration,
+		metrics.downloadDuration,
+		metrics.queued,
+		metrics.inFlight,
+	}
+}
+
+// methods are noop on nil metrics (client without metrics)
+
+func (metrics *Metrics) enqueue() {
+	if metrics != nil {
+		metrics.queued.Inc()
+	}
+}
+
+func (metrics *Metrics) dequeue() {
+	if metrics != nil {
+		metrics.queued.Dec()
+	}
+}
+
+func (metrics *Metrics) startDownload() {
+	if metrics != nil {
+		metrics.inFlight.Inc()
+	}
+}
+
+func (metrics *Metrics) finishDownload() {
+	if metrics != nil {
+		metrics.inFlight.Dec()
+	}
+}
+
+func (metrics *Metrics) observeRequest(backend string, duration time.Duration) {
+	if metrics != nil {
+		metrics.requestDuration.WithLabelValues(backend).Observe(duration.Seconds())
+	}
+}
+
+func (metrics *Metrics) retry(backend string) {
+	if metrics != nil {
+		metrics.retries.WithLabelValues(backend).Inc()
+	}
+}
+
+func (metrics *Metrics) observeDownload(stat DownStat) {
+	if metrics == nil {
+		return
+	}
+
+	backend := stat.Backend
+	if backend == "" {
+		backend = backendNone
+	}
+
+	metrics.downloads.WithLabelValues(stat.Status.String(), backend).Inc()
+	if stat.Status == DOWN_OK {
+		metrics.bytes.WithLabelValues(backend).Add(float64(stat.Size))
+		metrics.downloadDuration.WithLabelValues(backend).Observe(stat.Duration.Seconds())
+	}
+}
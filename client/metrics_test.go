@@ -0,0 +1,87 @@
+package storclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	missingSha, err := hashutil.StringToHash(sha256.New(), "01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b")
+	assert.NoError(t, err)
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer s3.Close()
+
+	failures := 0
+	stor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == fmt.Sprintf("/%s", missingSha) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if failures == 0 {
+			failures++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer stor.Close()
+
+	registry := prometheus.NewRegistry()
+	metrics, err := NewMetrics(registry)
+	assert.NoError(t, err)
+
+	_, err = NewMetrics(registry)
+	assert.Error(t, err, "metrics are already registered")
+
+	storURL, _ := url.Parse(stor.URL)
+	s3URL, _ := url.Parse(s3.URL)
+	client, err := New(*storURL, "some_dir", StorClientOpts{Devnull: true, Max: 1, RetryAttempts: 3, RetryDelay: time.Millisecond, S3URL: s3URL, Metrics: metrics})
+	assert.NoError(t, err)
+
+	client.Start()
+	defer func() {
+		assert.NoError(t, client.Close())
+	}()
+
+	client.Download(emptyHash)
+	client.Download(missingSha)
+	client.Wait()
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.downloads.WithLabelValues("ok", backendStor)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.downloads.WithLabelValues("fail", backendStor)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.bytes.WithLabelValues(backendStor)), "empty object")
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.retries.WithLabelValues(backendS3)), "s3 is first attempt")
+	assert.Equal(t, 3.0, testutil.ToFloat64(metrics.retries.WithLabelValues(backendStor)), "fallback to stor (2) and retry after 503 of stor")
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.queued))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.inFlight))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.requestDuration), "s3 and stor series")
+
+	exists, err := client.Exists(context.Background(), emptyHash)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestNilMetrics(t *testing.T) {
+	var metrics *Metrics
+
+	metrics.enqueue()
+	metrics.dequeue()
+	metrics.startDownload()
+	metrics.finishDownload()
+	metrics.retry(backendStor)
+	metrics.observeRequest(backendStor, time.Second)
+	metrics.observeDownload(DownStat{Status: DOWN_OK})
+}
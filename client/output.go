@@ -0,0 +1,97 @@
+package storclient
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/pkg/errors"
+)
+
+// Output is destination of downloaded objects
+type Output interface {
+	// Exists returns true if object of name is already in output (download is skipped)
+	Exists(name string) bool
+	// TempDir returns directory for temporary file of object of name
+	//
+	// empty string means default directory for temporary files
+	TempDir(name string) (string, error)
+	// Store moves downloaded (and verified) temporary file to output as object of name
+	Store(name string, tempfile pathutil.Path, modTime time.Time) error
+	// Close flushes and closes output
+	Close() error
+}
+
+type dirOutput struct {
+	dir string
+}
+
+// NewDirOutput returns output which stores every object as file in dir (default output)
+func NewDirOutput(dir string) Output {
+	return &dirOutput{dir: dir}
+}
+
+func (o *dirOutput) Exists(name string) bool {
+	path, err := pathutil.New(o.dir, name)
+	if err != nil {
+		return false
+	}
+
+	return path.Exists()
+}
+
+// TempDir returns (and creates) parent dir of object file
+func (o *dirOutput) TempDir(name string) (string, error) {
+	path, err := pathutil.New(o.dir, name)
+	if err != nil {
+		return "", errors.Wrap(err, "path problem")
+	}
+
+	return makeParentDir(path)
+}
+
+func (o *dirOutput) Store(name string, tempfile pathutil.Path, modTime time.Time) error {
+	path, err := pathutil.New(o.dir, name)
+	if err != nil {
+		return errors.Wrap(err, "path problem")
+	}
+
+	if _, err := tempfile.Rename(path.Canonpath()); err != nil {
+		return errors.Wrapf(err, "Rename temp %s to final path %s fail", tempfile, path)
+	}
+
+	if err := os.Chtimes(path.Canonpath(), modTime, modTime); err != nil {
+		return errors.Wrapf(err, "Chtimes(%s, %s) fail", path.Canonpath(), modTime.String())
+	}
+
+	return nil
+}
+
+func (o *dirOutput) Close() error {
+	return nil
+}
+
+func makeParentDir(path pathutil.Path) (string, error) {
+	parent := path.Parent().Canonpath()
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", errors.Wrapf(err, "Create of directory %s fail", parent)
+	}
+
+	return parent, nil
+}
+
+func copyFileTo(w io.Writer, file pathutil.Path) (err error) {
+	reader, err := os.Open(file.Canonpath())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := reader.Close(); errClose != nil {
+			err = errClose
+		}
+	}()
+
+	_, err = io.Copy(w, reader)
+	return err
+}
@@ -0,0 +1,136 @@
+package storclient
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// Compression of stream outputs
+type Compression string
+
+const (
+	CompressionNone Compression = ""
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+type tarOutput struct {
+	lock       sync.Mutex
+	writer     *tar.Writer
+	compressor io.WriteCloser
+	names      map[string]struct{}
+}
+
+// NewTarOutput returns output which writes every object as entry of one tar archive to w
+//
+// entries are written in order of finished downloads,
+// w isn't closed by Close of output
+func NewTarOutput(w io.Writer, compression Compression) (Output, error) {
+	compressor, err := newCompressor(w, compression)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &tarOutput{
+		names: make(map[string]struct{}),
+	}
+
+	if compressor != nil {
+		o.compressor = compressor
+		o.writer = tar.NewWriter(compressor)
+	} else {
+		o.writer = tar.NewWriter(w)
+	}
+
+	return o, nil
+}
+
+func newCompressor(w io.Writer, compression Compression) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return nil, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("Unknown compression %s", compression)
+	}
+}
+
+func (o *tarOutput) Exists(name string) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	_, ok := o.names[name]
+	return ok
+}
+
+func (o *tarOutput) TempDir(name string) (string, error) {
+	return "", nil
+}
+
+func (o *tarOutput) Store(name string, tempfile pathutil.Path, modTime time.Time) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if _, ok := o.names[name]; ok {
+		return tempfile.Remove()
+	}
+
+	if err := writeTarEntry(o.writer, name, tempfile, modTime); err != nil {
+		return err
+	}
+
+	o.names[name] = struct{}{}
+
+	return tempfile.Remove()
+}
+
+func writeTarEntry(writer *tar.Writer, name string, file pathutil.Path, modTime time.Time) error {
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     stat.Size(),
+		ModTime:  modTime,
+	}
+
+	if err := writer.WriteHeader(header); err != nil {
+		return errors.Wrapf(err, "Write tar header of %s fail", name)
+	}
+
+	if err := copyFileTo(writer, file); err != nil {
+		return errors.Wrapf(err, "Write tar entry %s fail", name)
+	}
+
+	return nil
+}
+
+func (o *tarOutput) Close() error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if err := o.writer.Close(); err != nil {
+		return err
+	}
+
+	if o.compressor != nil {
+		return o.compressor.Close()
+	}
+
+	return nil
+}
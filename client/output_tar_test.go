@@ -0,0 +1,66 @@
+package storclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTarOutput(t *testing.T) {
+	modTime := time.Date(2018, time.March, 20, 15, 48, 42, 0, time.UTC)
+
+	decompressors := map[Compression]func(io.Reader) (io.Reader, error){
+		CompressionNone: func(r io.Reader) (io.Reader, error) { return r, nil },
+		CompressionGzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		CompressionZstd: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+
+	for compression, decompressor := range decompressors {
+		t.Run(string(compression), func(t *testing.T) {
+			var archive bytes.Buffer
+			output, err := NewTarOutput(&archive, compression)
+			assert.NoError(t, err)
+
+			assert.False(t, output.Exists("a.dat"))
+			assert.NoError(t, output.Store("a.dat", newTempFileWithContent(t, "content"), modTime))
+			assert.True(t, output.Exists("a.dat"))
+			assert.NoError(t, output.Store("a.dat", newTempFileWithContent(t, "content"), modTime), "duplicate is skipped")
+			assert.NoError(t, output.Close())
+
+			r, err := decompressor(&archive)
+			assert.NoError(t, err)
+
+			tr := tar.NewReader(r)
+			header, err := tr.Next()
+			assert.NoError(t, err)
+			assert.Equal(t, "a.dat", header.Name)
+			assert.Equal(t, modTime, header.ModTime.UTC())
+
+			content, err := ioutil.ReadAll(tr)
+			assert.NoError(t, err)
+			assert.Equal(t, "content", string(content))
+
+			_, err = tr.Next()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+
+	_, err := NewTarOutput(ioutil.Discard, "rar")
+	assert.Error(t, err)
+}
+
+func newTempFileWithContent(t *testing.T, content string) pathutil.Path {
+	path, err := pathutil.NewTempFile(pathutil.TempOpt{})
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(path.Canonpath(), []byte(content), 0644))
+
+	return path
+}
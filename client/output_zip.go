@@ -0,0 +1,268 @@
+package storclient
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/rand"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/pkg/errors"
+)
+
+// DefaultZipPassword is conventional password of zip archives with malware samples
+const DefaultZipPassword = "infected"
+
+type zipOutput struct {
+	lock     sync.Mutex
+	writer   *zip.Writer
+	password string
+	names    map[string]struct{}
+}
+
+type zipDirOutput struct {
+	dir      string
+	password string
+}
+
+// NewZipOutput returns output which writes every object as entry of one zip archive to w
+//
+// entries are encrypted by password (traditional zip encryption),
+// empty password means without encryption
+//
+// w isn't closed by Close of output
+func NewZipOutput(w io.Writer, password string) Output {
+	return &zipOutput{
+		writer:   zip.NewWriter(w),
+		password: password,
+		names:    make(map[string]struct{}),
+	}
+}
+
+// NewZipDirOutput returns output which stores every object as own zip archive (name.zip) in dir
+//
+// archives are encrypted by password (traditional zip encryption),
+// empty password means without encryption
+func NewZipDirOutput(dir string, password string) Output {
+	return &zipDirOutput{dir: dir, password: password}
+}
+
+func (o *zipOutput) Exists(name string) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	_, ok := o.names[name]
+	return ok
+}
+
+func (o *zipOutput) TempDir(name string) (string, error) {
+	return "", nil
+}
+
+func (o *zipOutput) Store(name string, tempfile pathutil.Path, modTime time.Time) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if _, ok := o.names[name]; ok {
+		return tempfile.Remove()
+	}
+
+	if err := writeZipEntry(o.writer, name, tempfile, modTime, o.password); err != nil {
+		return err
+	}
+
+	o.names[name] = struct{}{}
+
+	return tempfile.Remove()
+}
+
+func (o *zipOutput) Close() error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	return o.writer.Close()
+}
+
+func (o *zipDirOutput) archivePath(name string) (pathutil.Path, error) {
+	return pathutil.New(o.dir, name+".zip")
+}
+
+func (o *zipDirOutput) Exists(name string) bool {
+	path, err := o.archivePath(name)
+	if err != nil {
+		return false
+	}
+
+	return path.Exists()
+}
+
+func (o *zipDirOutput) TempDir(name string) (string, error) {
+	path, err := o.archivePath(name)
+	if err != nil {
+		return "", errors.Wrap(err, "path problem")
+	}
+
+	return makeParentDir(path)
+}
+
+func (o *zipDirOutput) Store(name string, tempfile pathutil.Path, modTime time.Time) (err error) {
+	path, err := o.archivePath(name)
+	if err != nil {
+		return errors.Wrap(err, "path problem")
+	}
+
+	archive, err := ioutil.TempFile(path.Parent().Canonpath(), fmt.Sprintf("%s_*.zip.temp", filepath.Base(name)))
+	if err != nil {
+		return errors.Wrap(err, "Construct of new temp file fail")
+	}
+
+	// cleanup temporary archive if this function fail (err is set)
+	defer func() {
+		if err != nil {
+			_ = archive.Close()
+			_ = os.Remove(archive.Name())
+		}
+	}()
+
+	writer := zip.NewWriter(archive)
+	if err := writeZipEntry(writer, filepath.Base(name), tempfile, modTime, o.password); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(archive.Name(), path.Canonpath()); err != nil {
+		return errors.Wrapf(err, "Rename temp %s to final path %s fail", archive.Name(), path)
+	}
+
+	if err := os.Chtimes(path.Canonpath(), modTime, modTime); err != nil {
+		return errors.Wrapf(err, "Chtimes(%s, %s) fail", path.Canonpath(), modTime.String())
+	}
+
+	return tempfile.Remove()
+}
+
+func (o *zipDirOutput) Close() error {
+	return nil
+}
+
+// writeZipEntry writes content of file as deflated (and encrypted if password is set) entry
+//
+// encrypted entries are written raw, crc32 and compressed size are needed before content,
+// so file is deflated to temporary file (next to file) which is encrypted then
+func writeZipEntry(writer *zip.Writer, name string, file pathutil.Path, modTime time.Time, password string) (err error) {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	}
+
+	if password == "" {
+		entry, err := writer.CreateHeader(header)
+		if err != nil {
+			return errors.Wrapf(err, "Write zip header of %s fail", name)
+		}
+
+		return copyFileTo(entry, file)
+	}
+
+	compressed, err := ioutil.TempFile(filepath.Dir(file.Canonpath()), fmt.Sprintf("%s_*.zip.temp", filepath.Base(name)))
+	if err != nil {
+		return errors.Wrap(err, "Construct of new temp file fail")
+	}
+	defer func() {
+		if errClose := compressed.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+		_ = os.Remove(compressed.Name())
+	}()
+
+	crc := crc32.NewIEEE()
+	size, err := deflateFileTo(compressed, file, crc)
+	if err != nil {
+		return err
+	}
+
+	compressedSize, err := compressed.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	header.Flags |= 0x1
+	header.ModifiedDate, header.ModifiedTime = msDosTime(modTime)
+	header.CRC32 = crc.Sum32()
+	header.UncompressedSize64 = uint64(size)
+	header.CompressedSize64 = uint64(compressedSize + zipCryptoHeaderLen)
+
+	raw, err := writer.CreateRaw(header)
+	if err != nil {
+		return errors.Wrapf(err, "Write zip header of %s fail", name)
+	}
+
+	var encryptionHeader [zipCryptoHeaderLen]byte
+	if _, err := rand.Read(encryptionHeader[:]); err != nil {
+		return err
+	}
+
+	encrypted, err := newZipCryptoWriter(raw, password, encryptionHeader, header.CRC32)
+	if err != nil {
+		return err
+	}
+
+	if _, err := compressed.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(encrypted, compressed); err != nil {
+		return errors.Wrapf(err, "Write zip entry %s fail", name)
+	}
+
+	return nil
+}
+
+// deflateFileTo writes deflated content of file to w, uncompressed content is copied to plain
+func deflateFileTo(w io.Writer, file pathutil.Path, plain io.Writer) (size int64, err error) {
+	deflater, err := flate.NewWriter(w, flate.DefaultCompression)
+	if err != nil {
+		return 0, err
+	}
+
+	reader, err := os.Open(file.Canonpath())
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if errClose := reader.Close(); errClose != nil {
+			err = errClose
+		}
+	}()
+
+	size, err = io.Copy(io.MultiWriter(deflater, plain), reader)
+	if err != nil {
+		return 0, err
+	}
+
+	return size, deflater.Close()
+}
+
+// msDosTime converts t to MS-DOS date and time (CreateRaw doesn't do it)
+func msDosTime(t time.Time) (date uint16, tm uint16) {
+	t = t.UTC()
+	date = uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	tm = uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+
+	return date, tm
+}
@@ -0,0 +1,121 @@
+package storclient
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"hash/crc32"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZipOutput(t *testing.T) {
+	modTime := time.Date(2018, time.March, 20, 15, 48, 42, 0, time.UTC)
+
+	t.Run("encrypted", func(t *testing.T) {
+		var archive bytes.Buffer
+		output := NewZipOutput(&archive, DefaultZipPassword)
+
+		assert.False(t, output.Exists("a.dat"))
+		assert.NoError(t, output.Store("a.dat", newTempFileWithContent(t, "content"), modTime))
+		assert.True(t, output.Exists("a.dat"))
+		assert.NoError(t, output.Close())
+
+		assert.Equal(t, "content", readEncryptedZipEntry(t, archive.Bytes(), "a.dat", DefaultZipPassword))
+	})
+
+	t.Run("without password", func(t *testing.T) {
+		var archive bytes.Buffer
+		output := NewZipOutput(&archive, "")
+		assert.NoError(t, output.Store("a.dat", newTempFileWithContent(t, "content"), modTime))
+		assert.NoError(t, output.Close())
+
+		reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+		assert.NoError(t, err)
+		entry, err := reader.File[0].Open()
+		assert.NoError(t, err)
+		content, err := ioutil.ReadAll(entry)
+		assert.NoError(t, err)
+		assert.Equal(t, "content", string(content))
+	})
+
+	t.Run("archive per file", func(t *testing.T) {
+		tempdir, err := pathutil.NewTempDir(pathutil.TempOpt{})
+		assert.NoError(t, err)
+		defer func() {
+			assert.NoError(t, tempdir.RemoveTree())
+		}()
+
+		output := NewZipDirOutput(tempdir.Canonpath(), DefaultZipPassword)
+		assert.False(t, output.Exists("a.dat"))
+
+		tempfile := newTempFileWithContent(t, "content")
+		assert.NoError(t, output.Store("a.dat", tempfile, modTime))
+		assert.True(t, output.Exists("a.dat"))
+		assert.False(t, tempfile.Exists())
+
+		archivePath, err := tempdir.Child("a.dat.zip")
+		assert.NoError(t, err)
+		archive, err := ioutil.ReadFile(archivePath.Canonpath())
+		assert.NoError(t, err)
+
+		assert.Equal(t, "content", readEncryptedZipEntry(t, archive, "a.dat", DefaultZipPassword))
+
+		children, err := tempdir.Children()
+		assert.NoError(t, err)
+		assert.Len(t, children, 1)
+	})
+}
+
+// archive created by Info-ZIP (zip -P infected) with deflated a.dat (content of sample\n 20 times)
+const infoZipArchive = "UEsDBBQACQAIABV+dEyKjmq1JQAAAGgBAAAFAAAAYS5kYXSeFUT60lfP2juIoliLS48ozqVeAq5GDBstbbB5cfAKkSqkBSKKUEsHCIqOarUlAAAAaAEAAFBLAQIeAxQACQAIABV+dEyKjmq1JQAAAGgBAAAFAAAAAAAAAAEAAACkgQAAAABhLmRhdFBLBQYAAAAAAQABADMAAABYAAAAAAA="
+
+// traditional zip encryption (zipcrypto) is verified by archive of independent implementation
+func TestReadInfoZipArchive(t *testing.T) {
+	archive, err := base64.StdEncoding.DecodeString(infoZipArchive)
+	assert.NoError(t, err)
+
+	assert.Equal(t, strings.Repeat("content of sample\n", 20), readEncryptedZipEntry(t, archive, "a.dat", DefaultZipPassword))
+}
+
+func readEncryptedZipEntry(t *testing.T, archive []byte, name string, password string) string {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	assert.NoError(t, err)
+	assert.Len(t, reader.File, 1)
+
+	entry := reader.File[0]
+	assert.Equal(t, name, entry.Name)
+	assert.Equal(t, time.Date(2018, time.March, 20, 15, 48, 42, 0, time.UTC), entry.Modified.UTC())
+	assert.Equal(t, uint16(0x1), entry.Flags&0x1)
+	assert.Equal(t, zip.Deflate, entry.Method)
+
+	raw, err := entry.OpenRaw()
+	assert.NoError(t, err)
+	encrypted, err := ioutil.ReadAll(raw)
+	assert.NoError(t, err)
+
+	crypto := newZipCrypto(password)
+	for i, b := range encrypted {
+		encrypted[i] = b ^ crypto.streamByte()
+		crypto.updateKeys(encrypted[i])
+	}
+
+	// check byte is high byte of modification time with data descriptor (flag 0x8), of crc32 otherwise
+	check := byte(entry.CRC32 >> 24)
+	if entry.Flags&0x8 != 0 {
+		check = byte(entry.ModifiedTime >> 8)
+	}
+	assert.Equal(t, check, encrypted[zipCryptoHeaderLen-1], "check byte")
+
+	content, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(encrypted[zipCryptoHeaderLen:])))
+	assert.NoError(t, err)
+	assert.Equal(t, entry.CRC32, crc32.ChecksumIEEE(content))
+
+	return string(content)
+}
@@ -0,0 +1,33 @@
+package storclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Presign returns pre-signed url of GET of object on S3 valid for expires (max MaxPresignExpires),
+// e.g. temporary access to object for somebody without credentials
+//
+// key of object is created by S3Template and Version of object is used,
+// client must have S3 backend with credentials (StorClientOpts.S3)
+//
+// if verify is set, existence of object is checked by HEAD request first (missing object is returned as ErrNotFound)
+func (client *StorClient) Presign(ctx context.Context, object Object, expires time.Duration, verify bool) (string, error) {
+	if client.s3 == nil {
+		return "", fmt.Errorf("Pre-signed url needs S3 backend (StorClientOpts.S3)")
+	}
+
+	key, err := client.createS3Key(object)
+	if err != nil {
+		return "", err
+	}
+
+	if verify {
+		if _, err := client.s3.HeadObject(ctx, key, object.Version); err != nil {
+			return "", err
+		}
+	}
+
+	return client.s3.PresignGetObject(key, object.Version, expires)
+}
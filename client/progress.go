@@ -0,0 +1,69 @@
+package storclient
+
+import (
+	"sync/atomic"
+)
+
+// Progress is snapshot of progress of all downloads (all batches) of client
+type Progress struct {
+	// count of objects added to download queue
+	Queued int64
+	// count of downloaded objects
+	Done int64
+	// count of failed downloads
+	Failed int64
+	// count of skipped objects (already in output)
+	Skipped int64
+	// downloaded bytes
+	Size int64
+	// count of running downloads
+	Active int64
+}
+
+// Processed returns count of finished (downloaded, failed or skipped) objects
+func (progress Progress) Processed() int64 {
+	return progress.Done + progress.Failed + progress.Skipped
+}
+
+// Remaining returns count of queued objects which aren't finished yet
+func (progress Progress) Remaining() int64 {
+	return progress.Queued - progress.Processed()
+}
+
+// progressCounters are updated concurrently by batches and workers
+type progressCounters struct {
+	queued, done, failed, skipped, size, active int64
+}
+
+func (counters *progressCounters) start() {
+	atomic.AddInt64(&counters.active, 1)
+}
+
+func (counters *progressCounters) finish(stat DownStat) {
+	atomic.AddInt64(&counters.active, -1)
+	atomic.AddInt64(&counters.size, stat.Size)
+
+	switch stat.Status {
+	case DOWN_OK:
+		atomic.AddInt64(&counters.done, 1)
+	case DOWN_SKIP:
+		atomic.AddInt64(&counters.skipped, 1)
+	default:
+		atomic.AddInt64(&counters.failed, 1)
+	}
+}
+
+// Progress returns snapshot of progress of downloads, it is safe to call it concurrently with downloads
+// (e.g. periodically for progress bar)
+func (client *StorClient) Progress() Progress {
+	counters := client.progress
+
+	return Progress{
+		Queued:  atomic.LoadInt64(&counters.queued),
+		Done:    atomic.LoadInt64(&counters.done),
+		Failed:  atomic.LoadInt64(&counters.failed),
+		Skipped: atomic.LoadInt64(&counters.skipped),
+		Size:    atomic.LoadInt64(&counters.size),
+		Active:  atomic.LoadInt64(&counters.active),
+	}
+}
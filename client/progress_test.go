@@ -0,0 +1,47 @@
+package storclient_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	missingSha, err := hashutil.StringToHash(sha256.New(), "01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b")
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == fmt.Sprintf("/%s", missingSha) {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	url, _ := url.Parse(server.URL)
+	client, err := storclient.New(*url, "some_dir", storclient.StorClientOpts{Devnull: true, RetryAttempts: 1, RetryDelay: time.Millisecond})
+	assert.NoError(t, err)
+
+	assert.Equal(t, storclient.Progress{}, client.Progress())
+
+	client.Start()
+	defer func() {
+		assert.NoError(t, client.Close())
+	}()
+
+	client.Download(emptySha)
+	client.Download(missingSha)
+	client.Wait()
+
+	progress := client.Progress()
+	assert.Equal(t, storclient.Progress{Queued: 2, Done: 1, Failed: 1}, progress)
+	assert.Equal(t, int64(2), progress.Processed())
+	assert.Equal(t, int64(0), progress.Remaining())
+}
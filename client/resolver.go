@@ -0,0 +1,283 @@
+package storclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/avast/hashutil-go"
+)
+
+// types of hashes which can be resolved to sha256
+const (
+	HashMD5  = "md5"
+	HashSHA1 = "sha1"
+)
+
+// max size of response of mapping service
+const maxResolveResponseSize = 1 << 20
+
+// backend of HTTPStatusError of mapping service
+const backendResolver = "resolver"
+
+// sha256 (hex) as whole word of plain text response of mapping service
+var sha256Hex = regexp.MustCompile(`\b[a-fA-F0-9]{64}\b`)
+
+// HashResolver resolves md5 or sha1 (hex string) to sha256 of object
+//
+// unknown hash matches ErrNotFound, unexpected response of mapping service is *HTTPStatusError
+type HashResolver interface {
+	Resolve(ctx context.Context, hash string) (hashutil.Hash, error)
+}
+
+// HashType returns type of hex hash by its length (HashMD5, HashSHA1)
+// empty string means that hash can't be resolved
+func HashType(hash string) string {
+	switch len(hash) {
+	case 32:
+		return HashMD5
+	case 40:
+		return HashSHA1
+	default:
+		return ""
+	}
+}
+
+type mappingResolver struct {
+	mapping map[string]hashutil.Hash
+}
+
+// NewMappingResolver returns resolver with mapping read from CSV rd
+//
+// header of CSV is required, column sha256 and at least one of columns md5, sha1
+// (SQLite database can be used by NewSQLMappingResolver)
+func NewMappingResolver(rd io.Reader) (HashResolver, error) {
+	reader := csv.NewReader(rd)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Read of mapping header fail: %s", err)
+	}
+
+	return newMappingResolver(header, reader.Read)
+}
+
+// NewSQLMappingResolver returns resolver with mapping read by query from database db (e.g. SQLite),
+// driver of database is registered by caller
+//
+// query must return column sha256 and at least one of columns md5, sha1 (NULL is empty value), e.g.
+//
+//	SELECT md5, sha1, sha256 FROM samples
+func NewSQLMappingResolver(ctx context.Context, db *sql.DB, query string) (HashResolver, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("Query of mapping fail: %s", err)
+	}
+	defer rows.Close()
+
+	header, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]sql.NullString, len(header))
+	dest := make([]interface{}, len(header))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	return newMappingResolver(header, func() ([]string, error) {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		record := make([]string, len(values))
+		for i, value := range values {
+			record[i] = value.String
+		}
+
+		return record, nil
+	})
+}
+
+// newMappingResolver returns resolver with mapping of records (until io.EOF) with columns by header
+func newMappingResolver(header []string, next func() ([]string, error)) (HashResolver, error) {
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	shaIndex, ok := index["sha256"]
+	if !ok {
+		return nil, fmt.Errorf("Column sha256 is missing in mapping header")
+	}
+
+	keyIndexes := make([]int, 0, 2)
+	for _, hashType := range []string{HashMD5, HashSHA1} {
+		if i, ok := index[hashType]; ok {
+			keyIndexes = append(keyIndexes, i)
+		}
+	}
+
+	if len(keyIndexes) == 0 {
+		return nil, fmt.Errorf("Columns %s or %s are missing in mapping header", HashMD5, HashSHA1)
+	}
+
+	resolver := &mappingResolver{mapping: make(map[string]hashutil.Hash)}
+	for {
+		record, err := next()
+		if err == io.EOF {
+			return resolver, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if shaIndex >= len(record) {
+			continue
+		}
+
+		sha, err := hashutil.StringToHash(sha256.New(), strings.TrimSpace(record[shaIndex]))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid sha256 %q in mapping: %s", record[shaIndex], err)
+		}
+
+		for _, i := range keyIndexes {
+			if i < len(record) && strings.TrimSpace(record[i]) != "" {
+				resolver.mapping[strings.ToLower(strings.TrimSpace(record[i]))] = sha
+			}
+		}
+	}
+}
+
+func (r *mappingResolver) Resolve(ctx context.Context, hash string) (hashutil.Hash, error) {
+	sha, ok := r.mapping[strings.ToLower(hash)]
+	if !ok {
+		return hashutil.Hash{}, fmt.Errorf("Hash %s not found in mapping: %w", hash, ErrNotFound)
+	}
+
+	return sha, nil
+}
+
+type httpResolver struct {
+	urlTemplate *template.Template
+	httpClient  httpClient
+}
+
+type resolveParams struct {
+	// hash to resolve (lower case)
+	Hash string
+	// type of hash (HashMD5, HashSHA1)
+	Type string
+}
+
+// NewHTTPResolver returns resolver which asks mapping service
+//
+// urlTemplate is template of url of mapping service with fields Hash and Type (md5, sha1),
+// e.g. "http://mapping.domain.tld/{{.Type}}/{{.Hash}}"; response is JSON object with key sha256 (hex),
+// e.g. {"sha256": "..."}, or plain text with exactly one sha256 (hex), 404 means hash isn't known
+//
+// nil httpClient means http.DefaultClient
+func NewHTTPResolver(urlTemplate string, client *http.Client) (HashResolver, error) {
+	tmpl, err := template.New("resolve").Parse(urlTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("Parse of resolve url template fail: %s", err)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &httpResolver{urlTemplate: tmpl, httpClient: client}, nil
+}
+
+func (r *httpResolver) Resolve(ctx context.Context, hash string) (hashutil.Hash, error) {
+	params := resolveParams{Hash: strings.ToLower(hash), Type: HashType(hash)}
+	if params.Type == "" {
+		return hashutil.Hash{}, fmt.Errorf("Unknown type of hash %s", hash)
+	}
+
+	var url bytes.Buffer
+	if err := r.urlTemplate.Execute(&url, params); err != nil {
+		return hashutil.Hash{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url.String(), nil)
+	if err != nil {
+		return hashutil.Hash{}, err
+	}
+
+	resp, err := r.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return hashutil.Hash{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return hashutil.Hash{}, fmt.Errorf("Hash %s not found in mapping service: %w", hash, ErrNotFound)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return hashutil.Hash{}, &HTTPStatusError{Method: http.MethodGet, URL: url.String(), Backend: backendResolver, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResolveResponseSize))
+	if err != nil {
+		return hashutil.Hash{}, err
+	}
+
+	sha, err := parseResolveResponse(body)
+	if err != nil {
+		return hashutil.Hash{}, fmt.Errorf("Mapping service response of %s: %s", hash, err)
+	}
+
+	return hashutil.StringToHash(sha256.New(), sha)
+}
+
+// parseResolveResponse returns sha256 (hex) from JSON object (key sha256) or plain text response of mapping service
+func parseResolveResponse(body []byte) (string, error) {
+	trimmed := bytes.TrimSpace(body)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var response struct {
+			Sha256 string `json:"sha256"`
+		}
+		if err := json.Unmarshal(trimmed, &response); err != nil {
+			return "", fmt.Errorf("invalid JSON: %s", err)
+		}
+
+		if !sha256Hex.MatchString(response.Sha256) || len(response.Sha256) != hex.EncodedLen(sha256.Size) {
+			return "", fmt.Errorf("key sha256 doesn't contain sha256 (%q)", response.Sha256)
+		}
+
+		return response.Sha256, nil
+	}
+
+	shas := sha256Hex.FindAll(trimmed, -1)
+	switch len(shas) {
+	case 0:
+		return "", fmt.Errorf("doesn't contain sha256")
+	case 1:
+		return string(shas[0]), nil
+	default:
+		return "", fmt.Errorf("contains %d candidates of sha256", len(shas))
+	}
+}
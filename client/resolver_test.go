@@ -0,0 +1,149 @@
+package storclient
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+// md5 and sha1 of empty content
+const (
+	emptyMD5  = "d41d8cd98f00b204e9800998ecf8427e"
+	emptySHA1 = "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+)
+
+// md5 and sha1 of "hello"
+const (
+	helloMD5  = "5d41402abc4b2a76b9719d911017c592"
+	helloSHA1 = "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"
+)
+
+func TestHashType(t *testing.T) {
+	assert.Equal(t, HashMD5, HashType(emptyMD5))
+	assert.Equal(t, HashSHA1, HashType(emptySHA1))
+	assert.Equal(t, "", HashType(emptyHash.String()))
+}
+
+func TestMappingResolver(t *testing.T) {
+	mapping := "MD5,sha1,sha256\n" + strings.ToUpper(emptyMD5) + "," + emptySHA1 + "," + emptyHash.String() + "\n"
+
+	resolver, err := NewMappingResolver(strings.NewReader(mapping))
+	assert.NoError(t, err)
+
+	for _, hash := range []string{emptyMD5, emptySHA1, strings.ToUpper(emptySHA1)} {
+		sha, err := resolver.Resolve(context.Background(), hash)
+		assert.NoError(t, err)
+		assert.True(t, emptyHash.Equal(sha), hash)
+	}
+
+	_, err = resolver.Resolve(context.Background(), "00000000000000000000000000000000")
+	assert.True(t, errors.Is(err, ErrNotFound), "unknown hash")
+
+	_, err = NewMappingResolver(strings.NewReader("md5,sha1\n"))
+	assert.Error(t, err, "sha256 column is missing")
+
+	_, err = NewMappingResolver(strings.NewReader("sha256\n"))
+	assert.Error(t, err, "md5 and sha1 columns are missing")
+}
+
+func TestSQLMappingResolver(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	assert.NoError(t, err)
+	defer db.Close()
+	// in-memory database exists only in its connection
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE samples (hash_md5 TEXT, hash_sha1 TEXT, hash_sha256 TEXT)")
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO samples VALUES (?, NULL, ?)", strings.ToUpper(emptyMD5), emptyHash.String())
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO samples VALUES (NULL, ?, ?)", emptySHA1, emptyHash.String())
+	assert.NoError(t, err)
+
+	resolver, err := NewSQLMappingResolver(context.Background(), db, "SELECT hash_md5 AS md5, hash_sha1 AS sha1, hash_sha256 AS sha256 FROM samples")
+	assert.NoError(t, err)
+
+	for _, hash := range []string{emptyMD5, emptySHA1} {
+		sha, err := resolver.Resolve(context.Background(), hash)
+		assert.NoError(t, err)
+		assert.True(t, emptyHash.Equal(sha), hash)
+	}
+
+	_, err = resolver.Resolve(context.Background(), "00000000000000000000000000000000")
+	assert.True(t, errors.Is(err, ErrNotFound), "unknown hash")
+
+	_, err = NewSQLMappingResolver(context.Background(), db, "SELECT hash_md5 AS md5 FROM samples")
+	assert.Error(t, err, "sha256 column is missing")
+
+	_, err = NewSQLMappingResolver(context.Background(), db, "SELECT md5, sha256 FROM missing")
+	assert.Error(t, err, "missing table")
+}
+
+func TestHTTPResolver(t *testing.T) {
+	helloHash := testHash(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	sha512MD5 := "11111111111111111111111111111111"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/md5/" + emptyMD5:
+			fmt.Fprintf(w, `{"sha256": "%s"}`, emptyHash.String())
+		case "/md5/" + helloMD5:
+			fmt.Fprintf(w, `{"parent": "%s", "sha512": "%s", "sha256": "%s"}`, emptyHash, emptyHash.String()+emptyHash.String(), helloHash)
+		case "/sha1/" + emptySHA1:
+			w.Write([]byte("no sha"))
+		case "/sha1/" + helloSHA1:
+			fmt.Fprintf(w, "%s\n%s\n", emptyHash, helloHash)
+		case "/md5/" + sha512MD5:
+			fmt.Fprint(w, emptyHash.String()+emptyHash.String())
+		case "/md5/ffffffffffffffffffffffffffffffff":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	resolver, err := NewHTTPResolver(ts.URL+"/{{.Type}}/{{.Hash}}", nil)
+	assert.NoError(t, err)
+
+	sha, err := resolver.Resolve(context.Background(), strings.ToUpper(emptyMD5))
+	assert.NoError(t, err)
+	assert.True(t, emptyHash.Equal(sha))
+
+	sha, err = resolver.Resolve(context.Background(), helloMD5)
+	assert.NoError(t, err)
+	assert.True(t, helloHash.Equal(sha), "key sha256 of JSON")
+
+	_, err = resolver.Resolve(context.Background(), emptySHA1)
+	assert.Error(t, err, "response without sha256")
+	assert.False(t, errors.Is(err, ErrNotFound))
+
+	_, err = resolver.Resolve(context.Background(), helloSHA1)
+	assert.Error(t, err, "ambiguous response")
+
+	_, err = resolver.Resolve(context.Background(), sha512MD5)
+	assert.Error(t, err, "part of longer hash isn't sha256")
+
+	_, err = resolver.Resolve(context.Background(), "00000000000000000000000000000000")
+	assert.True(t, errors.Is(err, ErrNotFound), "not found")
+
+	_, err = resolver.Resolve(context.Background(), "ffffffffffffffffffffffffffffffff")
+	var statusErr *HTTPStatusError
+	if assert.True(t, errors.As(err, &statusErr)) {
+		assert.Equal(t, http.StatusInternalServerError, statusErr.StatusCode)
+		assert.Equal(t, ts.URL+"/md5/ffffffffffffffffffffffffffffffff", statusErr.URL)
+		assert.Equal(t, "Mapping service "+statusErr.URL+" return status code 500 (500 Internal Server Error)", err.Error())
+	}
+	assert.False(t, errors.Is(err, ErrNotFound))
+
+	_, err = resolver.Resolve(context.Background(), "abc")
+	assert.Error(t, err, "unknown type of hash")
+}
@@ -0,0 +1,384 @@
+package storclient
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerRequestPayer = "X-Amz-Request-Payer"
+	headerVersionID    = "X-Amz-Version-Id"
+
+	// max count of keys in response of ListObjectsV2 (default of S3)
+	DefaultS3MaxKeys = 1000
+)
+
+// S3Config is configuration of native S3 backend (see StorClientOpts.S3 and NewS3Client),
+// e.g. bucket of AWS or S3-compatible server (MinIO, Ceph)
+//
+//	storclient.S3Config{
+//		Endpoint:    minioURL, // http://127.0.0.1:9000
+//		Bucket:      "samples",
+//		PathStyle:   true,
+//		Credentials: storclient.StaticCredentials("minioadmin", "minioadmin", ""),
+//	}
+type S3Config struct {
+	// endpoint of S3 API without bucket, e.g. http://127.0.0.1:9000
+	// default (nil) is https://s3.<Region>.amazonaws.com
+	Endpoint *url.URL
+	// bucket with objects (required)
+	Bucket string
+	// region of bucket (signing of requests)
+	// default ("") is DefaultRegion
+	Region string
+	// path-style addressing (<endpoint>/<bucket>/<key>) instead of virtual-host style (<bucket>.<endpoint>/<key>),
+	// S3-compatible servers usually need path style
+	PathStyle bool
+	// credentials of AWS Signature V4 signing
+	// default (nil) means anonymous requests
+	Credentials CredentialsProvider
+	// requester (owner of credentials) pays for requests and transfer (bucket with Requester Pays)
+	RequesterPays bool
+	// custom headers of requests (signed by Credentials), e.g. x-amz-expected-bucket-owner
+	Header http.Header
+}
+
+// S3Client is client of S3 API of one bucket (GET, HEAD and ListObjectsV2 requests)
+//
+// requests aren't retried (retry of downloads is done by StorClient)
+type S3Client struct {
+	config     S3Config
+	httpClient httpClient
+	signer     *SigV4Signer
+	// authentication of requests without Credentials (StorClientOpts.S3Auth)
+	auth Authenticator
+}
+
+// S3ObjectInfo is metadata of object on S3 (from HEAD or ListObjectsV2)
+type S3ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	// version of object (versioned bucket), empty if bucket isn't versioned
+	VersionID string
+	// storage class of object (only ListObjectsV2)
+	StorageClass string
+}
+
+// S3ListInput is input of ListObjectsV2
+type S3ListInput struct {
+	// list only keys with prefix
+	Prefix string
+	// list keys after this key
+	StartAfter string
+	// token of next page (S3ListResult.NextContinuationToken)
+	ContinuationToken string
+	// max count of keys in result
+	// default (0) is DefaultS3MaxKeys
+	MaxKeys int
+}
+
+// S3ListResult is page of result of ListObjectsV2
+type S3ListResult struct {
+	Objects []S3ObjectInfo
+	// there are more keys, next page is requested with NextContinuationToken
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// S3Error is error response of S3 (unexpected status code with optional XML error)
+//
+// 404 matches ErrNotFound
+type S3Error struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// code of error, e.g. NoSuchKey, AccessDenied, SignatureDoesNotMatch (empty if response hasn't body)
+	Code    string
+	Message string
+}
+
+func (err *S3Error) Error() string {
+	if err.Code == "" {
+		return fmt.Sprintf("S3 %s %s fail %d", err.Method, err.URL, err.StatusCode)
+	}
+
+	return fmt.Sprintf("S3 %s %s fail %d %s: %s", err.Method, err.URL, err.StatusCode, err.Code, err.Message)
+}
+
+// Is returns true for ErrNotFound if status code is 404
+func (err *S3Error) Is(target error) bool {
+	return target == ErrNotFound && err.StatusCode == http.StatusNotFound
+}
+
+// NewS3Client returns client of S3 bucket, nil httpClient means http.DefaultClient
+func NewS3Client(config S3Config, client *http.Client) (*S3Client, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return newS3Client(config, client)
+}
+
+func newS3Client(config S3Config, httpClient httpClient) (*S3Client, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("Bucket of S3 is required")
+	}
+
+	if config.Region == "" {
+		config.Region = DefaultRegion
+	}
+
+	if config.Endpoint == nil {
+		config.Endpoint = &url.URL{Scheme: "https", Host: fmt.Sprintf("s3.%s.amazonaws.com", config.Region)}
+	}
+
+	s3 := &S3Client{config: config, httpClient: httpClient}
+	if config.Credentials != nil {
+		s3.signer = NewSigV4Signer(config.Credentials, config.Region)
+	}
+
+	return s3, nil
+}
+
+// bucketURL returns url of bucket by addressing style
+func (s3 *S3Client) bucketURL() url.URL {
+	u := *s3.config.Endpoint
+	u.RawQuery = ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	if s3.config.PathStyle {
+		u.Path += "/" + s3.config.Bucket
+	} else {
+		u.Host = s3.config.Bucket + "." + u.Host
+	}
+
+	return u
+}
+
+// ObjectURL returns url of key (object), version (versionId) is optional
+func (s3 *S3Client) ObjectURL(key string, version string) string {
+	u := s3.bucketURL()
+	u.Path += "/" + strings.TrimLeft(key, "/")
+	if version != "" {
+		u.RawQuery = url.Values{"versionId": []string{version}}.Encode()
+	}
+
+	return u.String()
+}
+
+// Authenticate sets custom headers (Header), header of requester pays (RequesterPays) and signs req by Credentials,
+// so S3Client is Authenticator of requests to its bucket
+func (s3 *S3Client) Authenticate(req *http.Request) error {
+	for name, values := range s3.config.Header {
+		req.Header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+	}
+
+	if s3.config.RequesterPays {
+		req.Header.Set(headerRequestPayer, "requester")
+	}
+
+	if s3.auth != nil {
+		if err := s3.auth.Authenticate(req); err != nil {
+			return err
+		}
+	}
+
+	if s3.signer == nil {
+		return nil
+	}
+
+	return s3.signer.Authenticate(req)
+}
+
+// PresignGetObject returns pre-signed url of GET of key (version is optional) valid for expires (see SigV4Signer.Presign),
+// Credentials are required
+func (s3 *S3Client) PresignGetObject(key string, version string, expires time.Duration) (string, error) {
+	if s3.signer == nil {
+		return "", fmt.Errorf("Pre-signed url needs credentials of S3")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s3.ObjectURL(key, version), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if s3.config.RequesterPays {
+		query := req.URL.Query()
+		query.Set(strings.ToLower(headerRequestPayer), "requester")
+		req.URL.RawQuery = query.Encode()
+	}
+
+	return s3.signer.Presign(req, expires)
+}
+
+// do sends authenticated request, response with other status than 200 is returned as *S3Error
+func (s3 *S3Client) do(ctx context.Context, method string, u string) (*http.Response, error) {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s3.Authenticate(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := s3.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newS3Error(method, u, resp)
+	}
+
+	return resp, nil
+}
+
+// newS3Error returns error of response (with code and message of XML error if body has it)
+func newS3Error(method string, u string, resp *http.Response) *S3Error {
+	s3Err := &S3Error{Method: method, URL: u, StatusCode: resp.StatusCode}
+
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024)); err == nil && xml.Unmarshal(data, &body) == nil {
+		s3Err.Code = body.Code
+		s3Err.Message = body.Message
+	}
+
+	return s3Err
+}
+
+// GetObject returns content of key (object), version is optional
+//
+// content isn't verified (see StorClient.Get), caller must close it
+func (s3 *S3Client) GetObject(ctx context.Context, key string, version string) (io.ReadCloser, error) {
+	resp, err := s3.do(ctx, http.MethodGet, s3.ObjectURL(key, version))
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// HeadObject returns metadata of key (object), version is optional
+func (s3 *S3Client) HeadObject(ctx context.Context, key string, version string) (S3ObjectInfo, error) {
+	resp, err := s3.do(ctx, http.MethodHead, s3.ObjectURL(key, version))
+	if err != nil {
+		return S3ObjectInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	info := S3ObjectInfo{
+		Key:       key,
+		Size:      resp.ContentLength,
+		ETag:      resp.Header.Get("ETag"),
+		VersionID: resp.Header.Get(headerVersionID),
+	}
+
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		if info.LastModified, err = http.ParseTime(lastModified); err != nil {
+			return S3ObjectInfo{}, fmt.Errorf("Invalid Last-Modified %q of %s: %s", lastModified, key, err)
+		}
+	}
+
+	return info, nil
+}
+
+// listBucketResult is XML response of ListObjectsV2
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+		Size         int64     `xml:"Size"`
+		StorageClass string    `xml:"StorageClass"`
+	} `xml:"Contents"`
+}
+
+// ListObjectsV2 returns one page of keys of bucket (see ListObjects for all pages)
+func (s3 *S3Client) ListObjectsV2(ctx context.Context, input S3ListInput) (S3ListResult, error) {
+	query := url.Values{"list-type": []string{"2"}}
+	if input.Prefix != "" {
+		query.Set("prefix", input.Prefix)
+	}
+	if input.StartAfter != "" {
+		query.Set("start-after", input.StartAfter)
+	}
+	if input.ContinuationToken != "" {
+		query.Set("continuation-token", input.ContinuationToken)
+	}
+	if input.MaxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(input.MaxKeys))
+	}
+
+	u := s3.bucketURL()
+	u.Path += "/"
+	u.RawQuery = query.Encode()
+
+	resp, err := s3.do(ctx, http.MethodGet, u.String())
+	if err != nil {
+		return S3ListResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var body listBucketResult
+	if err := xml.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return S3ListResult{}, fmt.Errorf("Invalid response of ListObjectsV2: %s", err)
+	}
+
+	result := S3ListResult{
+		Objects:               make([]S3ObjectInfo, 0, len(body.Contents)),
+		IsTruncated:           body.IsTruncated,
+		NextContinuationToken: body.NextContinuationToken,
+	}
+	for _, content := range body.Contents {
+		result.Objects = append(result.Objects, S3ObjectInfo{
+			Key:          content.Key,
+			Size:         content.Size,
+			LastModified: content.LastModified,
+			ETag:         content.ETag,
+			StorageClass: content.StorageClass,
+		})
+	}
+
+	return result, nil
+}
+
+// ListObjects calls fn for every key with prefix (all pages of ListObjectsV2), error of fn stops listing
+func (s3 *S3Client) ListObjects(ctx context.Context, prefix string, fn func(info S3ObjectInfo) error) error {
+	input := S3ListInput{Prefix: prefix}
+	for {
+		result, err := s3.ListObjectsV2(ctx, input)
+		if err != nil {
+			return err
+		}
+
+		for _, info := range result.Objects {
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+
+		input.ContinuationToken = result.NextContinuationToken
+	}
+}
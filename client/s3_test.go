@@ -0,0 +1,386 @@
+package storclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is S3-compatible stand-in (path style) with versioned objects of one bucket,
+// requests are verified by verifySigV4 (or verifyPresigned), requester pays header (or query) is required if requesterPays is set
+type fakeS3 struct {
+	bucket        string
+	keys          map[string]string
+	requesterPays bool
+	// versions of objects (key -> version -> content), latest version is in objects
+	objects  map[string]string
+	versions map[string]map[string]string
+}
+
+func (s *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s message</Message></Error>", code, code)
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	verify := verifySigV4
+	if r.URL.Query().Get("X-Amz-Signature") != "" {
+		verify = verifyPresigned
+	}
+	if err := verify(r, s.keys); err != nil {
+		s.error(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	if s.requesterPays && r.Header.Get("X-Amz-Request-Payer") != "requester" && r.URL.Query().Get("x-amz-request-payer") != "requester" {
+		s.error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	prefix := "/" + s.bucket + "/"
+	if r.URL.Path == "/"+s.bucket || r.URL.Path == prefix {
+		s.list(w, r)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		s.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+	content, ok := s.objects[key]
+	version := "latest"
+	if versionID := r.URL.Query().Get("versionId"); versionID != "" {
+		content, ok = s.versions[key][versionID]
+		version = versionID
+	}
+	if !ok {
+		s.error(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("Last-Modified", time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC).Format(http.TimeFormat))
+	w.Header().Set("ETag", `"etag"`)
+	w.Header().Set("X-Amz-Version-Id", version)
+	if r.Method == http.MethodGet {
+		fmt.Fprint(w, content)
+	}
+}
+
+func (s *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("list-type") != "2" {
+		s.error(w, http.StatusBadRequest, "InvalidArgument")
+		return
+	}
+
+	keys := []string{}
+	for key := range s.objects {
+		if strings.HasPrefix(key, query.Get("prefix")) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	start, _ := strconv.Atoi(query.Get("continuation-token"))
+	maxKeys, err := strconv.Atoi(query.Get("max-keys"))
+	if err != nil {
+		maxKeys = DefaultS3MaxKeys
+	}
+
+	end := start + maxKeys
+	if end > len(keys) {
+		end = len(keys)
+	}
+
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult>`)
+	for _, key := range keys[start:end] {
+		fmt.Fprintf(w, "<Contents><Key>%s</Key><LastModified>2018-06-01T12:00:00.000Z</LastModified><ETag>&quot;etag&quot;</ETag><Size>%d</Size><StorageClass>STANDARD</StorageClass></Contents>", key, len(s.objects[key]))
+	}
+	if end < len(keys) {
+		fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", end)
+	} else {
+		fmt.Fprint(w, "<IsTruncated>false</IsTruncated>")
+	}
+	fmt.Fprint(w, "</ListBucketResult>")
+}
+
+func newFakeS3(t *testing.T, requesterPays bool) (*httptest.Server, S3Config) {
+	s3 := &fakeS3{
+		bucket:        "samples",
+		keys:          map[string]string{"AKID": "SECRET"},
+		requesterPays: requesterPays,
+		objects: map[string]string{
+			"2c/f2/4d/2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824": "hello",
+			"a/1": "a1",
+			"a/2": "a2",
+			"a/3": "a3",
+			"b/1": "b1",
+		},
+		versions: map[string]map[string]string{
+			"2c/f2/4d/2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824": {"v1": "hello"},
+			"a/1": {"v1": "old a1"},
+		},
+	}
+
+	server := httptest.NewServer(s3)
+	endpoint, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	return server, S3Config{
+		Endpoint:      endpoint,
+		Bucket:        "samples",
+		Region:        "eu-central-1",
+		PathStyle:     true,
+		Credentials:   StaticCredentials("AKID", "SECRET", ""),
+		RequesterPays: requesterPays,
+	}
+}
+
+func TestS3ClientObjectURL(t *testing.T) {
+	_, err := NewS3Client(S3Config{}, nil)
+	assert.Error(t, err, "bucket is required")
+
+	s3, err := NewS3Client(S3Config{Bucket: "samples", Region: "eu-central-1"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://samples.s3.eu-central-1.amazonaws.com/a/b", s3.ObjectURL("a/b", ""))
+	assert.Equal(t, "https://samples.s3.eu-central-1.amazonaws.com/a/b?versionId=v%2B1", s3.ObjectURL("/a/b", "v+1"))
+
+	endpoint, err := url.Parse("http://127.0.0.1:9000/")
+	assert.NoError(t, err)
+	s3, err = NewS3Client(S3Config{Endpoint: endpoint, Bucket: "samples", PathStyle: true}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:9000/samples/a/b", s3.ObjectURL("a/b", ""))
+	assert.Equal(t, DefaultRegion, s3.config.Region)
+}
+
+func TestS3Client(t *testing.T) {
+	server, config := newFakeS3(t, true)
+	defer server.Close()
+
+	s3, err := NewS3Client(config, nil)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("head", func(t *testing.T) {
+		info, err := s3.HeadObject(ctx, "a/1", "")
+		assert.NoError(t, err)
+		assert.Equal(t, S3ObjectInfo{Key: "a/1", Size: 2, ETag: `"etag"`, VersionID: "latest", LastModified: time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)}, info)
+
+		info, err = s3.HeadObject(ctx, "a/1", "v1")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(6), info.Size)
+		assert.Equal(t, "v1", info.VersionID)
+
+		_, err = s3.HeadObject(ctx, "missing", "")
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("get", func(t *testing.T) {
+		reader, err := s3.GetObject(ctx, "a/1", "v1")
+		assert.NoError(t, err)
+		content, err := ioutil.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.NoError(t, reader.Close())
+		assert.Equal(t, "old a1", string(content))
+
+		_, err = s3.GetObject(ctx, "missing", "")
+		var s3Err *S3Error
+		if assert.True(t, errors.As(err, &s3Err)) {
+			assert.Equal(t, http.StatusNotFound, s3Err.StatusCode)
+			assert.Equal(t, "NoSuchKey", s3Err.Code)
+			assert.Contains(t, err.Error(), "NoSuchKey: NoSuchKey message")
+		}
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		result, err := s3.ListObjectsV2(ctx, S3ListInput{Prefix: "a/", MaxKeys: 2})
+		assert.NoError(t, err)
+		assert.True(t, result.IsTruncated)
+		if assert.Len(t, result.Objects, 2) {
+			assert.Equal(t, S3ObjectInfo{Key: "a/1", Size: 2, ETag: `"etag"`, StorageClass: "STANDARD", LastModified: time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)}, result.Objects[0])
+		}
+
+		var keys []string
+		assert.NoError(t, s3.ListObjects(ctx, "a/", func(info S3ObjectInfo) error {
+			keys = append(keys, info.Key)
+			return nil
+		}))
+		assert.Equal(t, []string{"a/1", "a/2", "a/3"}, keys)
+
+		stop := errors.New("stop")
+		assert.Equal(t, stop, s3.ListObjects(ctx, "", func(info S3ObjectInfo) error { return stop }))
+	})
+
+	t.Run("requester pays and credentials", func(t *testing.T) {
+		withoutPayer := config
+		withoutPayer.RequesterPays = false
+		s3, err := NewS3Client(withoutPayer, nil)
+		assert.NoError(t, err)
+
+		_, err = s3.HeadObject(ctx, "a/1", "")
+		var s3Err *S3Error
+		if assert.True(t, errors.As(err, &s3Err)) {
+			assert.Equal(t, http.StatusForbidden, s3Err.StatusCode)
+		}
+
+		wrongKey := config
+		wrongKey.Credentials = StaticCredentials("AKID", "WRONG", "")
+		s3, err = NewS3Client(wrongKey, nil)
+		assert.NoError(t, err)
+
+		_, err = s3.ListObjectsV2(ctx, S3ListInput{})
+		if assert.True(t, errors.As(err, &s3Err)) {
+			assert.Equal(t, "SignatureDoesNotMatch", s3Err.Code)
+		}
+	})
+}
+
+func TestS3Backend(t *testing.T) {
+	helloSha := testHash(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	missingSha := testHash(t, "01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b")
+
+	s3, config := newFakeS3(t, true)
+	defer s3.Close()
+
+	stor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	defer stor.Close()
+
+	storURL, err := url.Parse(stor.URL)
+	assert.NoError(t, err)
+
+	_, err = New(*storURL, "", StorClientOpts{S3: &config, S3URL: storURL})
+	assert.Error(t, err, "S3 and S3URL can't be combined")
+
+	tempdir, err := ioutil.TempDir("", "s3")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	client, err := New(*storURL, tempdir, StorClientOpts{S3: &config, RetryAttempts: 2, RetryDelay: time.Millisecond})
+	assert.NoError(t, err)
+
+	info, err := client.Stat(context.Background(), helloSha)
+	assert.NoError(t, err)
+	assert.Equal(t, s3.URL+"/samples/2c/f2/4d/"+helloSha.String(), info.URL)
+
+	client.Start()
+	client.DownloadObject(Object{Sha: helloSha, Version: "v1"})
+	client.DownloadObject(Object{Sha: missingSha})
+	total := client.Wait()
+	assert.NoError(t, client.Close())
+
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, 1, total.NotFound, "missing sha isn't on S3 nor stor")
+	assert.Equal(t, 1, total.Backends[backendS3].Fallbacks)
+
+	content, err := ioutil.ReadFile(tempdir + "/" + helloSha.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+}
+
+func TestS3BackendAuthentication(t *testing.T) {
+	helloSha := testHash(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	worldSha := testHash(t, "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7")
+
+	// bucket without permission to list returns AccessDenied for missing key
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "key" || r.Header.Get("X-Amz-Expected-Bucket-Owner") != "123" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.URL.Path != "/samples/2c/f2/4d/"+helloSha.String() {
+			(&fakeS3{}).error(w, http.StatusForbidden, "AccessDenied")
+			return
+		}
+
+		w.Header().Set("Content-Length", "5")
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, "hello")
+		}
+	}))
+	defer s3.Close()
+
+	stor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+worldSha.String() {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprint(w, "world")
+	}))
+	defer stor.Close()
+
+	storURL, err := url.Parse(stor.URL)
+	assert.NoError(t, err)
+	s3URL, err := url.Parse(s3.URL)
+	assert.NoError(t, err)
+
+	config := S3Config{
+		Endpoint:  s3URL,
+		Bucket:    "samples",
+		Region:    "eu-central-1",
+		PathStyle: true,
+		Header:    http.Header{"x-amz-expected-bucket-owner": []string{"123"}},
+	}
+	auth := HeaderAuth(http.Header{"X-Api-Key": []string{"key"}})
+
+	signed := config
+	signed.Credentials = StaticCredentials("AKID", "SECRET", "")
+	_, err = New(*storURL, "", StorClientOpts{S3: &signed, S3Auth: auth})
+	assert.Error(t, err, "S3Auth and S3.Credentials can't be combined")
+
+	tempdir, err := ioutil.TempDir("", "s3")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	client, err := New(*storURL, tempdir, StorClientOpts{S3: &config, S3Auth: auth, RetryAttempts: 2, RetryDelay: time.Millisecond})
+	assert.NoError(t, err)
+
+	info, err := client.Stat(context.Background(), helloSha)
+	assert.NoError(t, err, "HEAD request is authenticated by S3Auth too")
+	assert.Equal(t, int64(5), info.Size)
+
+	client.Start()
+	client.Download(helloSha)
+	client.Download(worldSha)
+	total := client.Wait()
+	assert.NoError(t, client.Close())
+
+	assert.Equal(t, 2, total.Count)
+	assert.Equal(t, 0, total.Fail)
+	assert.Equal(t, 1, total.Backends[backendS3].Fallbacks, "AccessDenied of S3 falls back to stor")
+	assert.Equal(t, int64(5), total.Backends[backendStor].Size)
+
+	for _, sha := range []hashutil.Hash{helloSha, worldSha} {
+		_, err := os.Stat(tempdir + "/" + sha.String())
+		assert.NoError(t, err)
+	}
+}
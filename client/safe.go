@@ -0,0 +1,209 @@
+package storclient
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+)
+
+// Wrapping of content of stored files
+type Wrapping string
+
+const (
+	// WrapNone - content is stored as is
+	WrapNone Wrapping = ""
+	// WrapXOR - content is xored by key
+	WrapXOR Wrapping = "xor"
+	// WrapAES - content is encrypted by AES-256 (CTR mode), sha256 of key is used as AES key
+	WrapAES Wrapping = "aes"
+)
+
+// SafeFileMode is mode of files stored by safe output (read-only, without execute bits)
+const SafeFileMode os.FileMode = 0444
+
+// wrapped file starts with magic, then follows wrapping type (one byte) and for AES IV
+var wrapMagic = []byte("STORWRAP")
+
+var wrapTypes = map[Wrapping]byte{
+	WrapXOR: 'x',
+	WrapAES: 'a',
+}
+
+// SafeOpts are options of safe output
+type SafeOpts struct {
+	// wrapping of stored content
+	Wrapping Wrapping
+	// key of wrapping (required for WrapXOR and WrapAES)
+	Key []byte
+}
+
+type safeDirOutput struct {
+	dirOutput
+	SafeOpts
+}
+
+// NewSafeDirOutput returns output which stores every object as read-only file without execute bits (SafeFileMode) in dir,
+// so sample can't be executed by accident
+//
+// content is optionally wrapped (see Wrapping), original content can be restored by Unwrap
+func NewSafeDirOutput(dir string, opts SafeOpts) (Output, error) {
+	if _, ok := wrapTypes[opts.Wrapping]; !ok && opts.Wrapping != WrapNone {
+		return nil, fmt.Errorf("Unknown wrapping %s", opts.Wrapping)
+	}
+
+	if opts.Wrapping != WrapNone && len(opts.Key) == 0 {
+		return nil, fmt.Errorf("Wrapping %s requires key", opts.Wrapping)
+	}
+
+	return &safeDirOutput{dirOutput: dirOutput{dir: dir}, SafeOpts: opts}, nil
+}
+
+func (o *safeDirOutput) Store(name string, tempfile pathutil.Path, modTime time.Time) (err error) {
+	if o.Wrapping != WrapNone {
+		wrapped, err := pathutil.NewTempFile(pathutil.TempOpt{Dir: tempfile.Parent().Canonpath(), Prefix: fmt.Sprintf("%s_*.wrap.temp", filepath.Base(name))})
+		if err != nil {
+			return errors.Wrap(err, "Construct of new temp file fail")
+		}
+
+		if err := wrapFile(tempfile, wrapped, o.SafeOpts); err != nil {
+			_ = wrapped.Remove()
+			return err
+		}
+
+		if err := tempfile.Remove(); err != nil {
+			_ = wrapped.Remove()
+			return err
+		}
+
+		tempfile = wrapped
+	}
+
+	if err := os.Chmod(tempfile.Canonpath(), SafeFileMode); err != nil {
+		return errors.Wrapf(err, "Chmod of %s fail", tempfile)
+	}
+
+	return o.dirOutput.Store(name, tempfile, modTime)
+}
+
+func wrapFile(src, dst pathutil.Path, opts SafeOpts) (err error) {
+	out, err := dst.OpenWriter()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := out.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+	}()
+
+	header := append(append([]byte{}, wrapMagic...), wrapTypes[opts.Wrapping])
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+
+	wrapper, err := newWrapWriter(out, opts)
+	if err != nil {
+		return err
+	}
+
+	return copyFileTo(wrapper, src)
+}
+
+func newWrapWriter(w io.Writer, opts SafeOpts) (io.Writer, error) {
+	switch opts.Wrapping {
+	case WrapXOR:
+		return cipher.StreamWriter{S: &xorStream{key: opts.Key}, W: w}, nil
+	case WrapAES:
+		iv := make([]byte, aes.BlockSize)
+		if _, err := rand.Read(iv); err != nil {
+			return nil, err
+		}
+
+		if _, err := w.Write(iv); err != nil {
+			return nil, err
+		}
+
+		stream, err := newAESStream(opts.Key, iv)
+		if err != nil {
+			return nil, err
+		}
+
+		return cipher.StreamWriter{S: stream, W: w}, nil
+	default:
+		return nil, fmt.Errorf("Unknown wrapping %s", opts.Wrapping)
+	}
+}
+
+// Unwrap writes original content of wrapped r (file stored by NewSafeDirOutput) to w
+// and verifies that hash (algorithm) of original content is expectedSha
+//
+// wrapping type is read from r, key must be same as key used for wrapping
+func Unwrap(r io.Reader, w io.Writer, key []byte, expectedSha hashutil.Hash, algorithm Algorithm) error {
+	header := make([]byte, len(wrapMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return errors.Wrap(err, "Read of wrap header fail")
+	}
+
+	if !bytes.Equal(header[:len(wrapMagic)], wrapMagic) {
+		return fmt.Errorf("Content isn't wrapped")
+	}
+
+	var stream cipher.Stream
+	switch header[len(wrapMagic)] {
+	case wrapTypes[WrapXOR]:
+		stream = &xorStream{key: key}
+	case wrapTypes[WrapAES]:
+		iv := make([]byte, aes.BlockSize)
+		if _, err := io.ReadFull(r, iv); err != nil {
+			return errors.Wrap(err, "Read of IV fail")
+		}
+
+		var err error
+		if stream, err = newAESStream(key, iv); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unknown wrapping type %q", header[len(wrapMagic)])
+	}
+
+	hasher := algorithm.New()
+	if _, err := io.Copy(io.MultiWriter(w, hasher), cipher.StreamReader{S: stream, R: r}); err != nil {
+		return err
+	}
+
+	return verifySha(hasher, expectedSha)
+}
+
+func newAESStream(key []byte, iv []byte) (cipher.Stream, error) {
+	aesKey := sha256.Sum256(key)
+	block, err := aes.NewCipher(aesKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewCTR(block, iv), nil
+}
+
+// xorStream is cipher.Stream xoring by repeated key
+type xorStream struct {
+	key []byte
+	pos int
+}
+
+func (s *xorStream) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		dst[i] = src[i] ^ s.key[s.pos]
+		s.pos = (s.pos + 1) % len(s.key)
+	}
+}
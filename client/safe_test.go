@@ -0,0 +1,77 @@
+package storclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSafeDirOutput(t *testing.T) {
+	content := "MZ sample"
+	hasher := sha256.New()
+	_, _ = hasher.Write([]byte(content))
+	sha, err := hashutil.BytesToHash(sha256.New(), hasher.Sum(nil))
+	assert.NoError(t, err)
+
+	for _, wrapping := range []Wrapping{WrapNone, WrapXOR, WrapAES} {
+		t.Run(string(wrapping), func(t *testing.T) {
+			tempdir, err := pathutil.NewTempDir(pathutil.TempOpt{})
+			assert.NoError(t, err)
+			defer func() {
+				assert.NoError(t, tempdir.RemoveTree())
+			}()
+
+			key := []byte("secret")
+			output, err := NewSafeDirOutput(tempdir.Canonpath(), SafeOpts{Wrapping: wrapping, Key: key})
+			assert.NoError(t, err)
+
+			tempfile, err := pathutil.NewTempFile(pathutil.TempOpt{Dir: tempdir.Canonpath()})
+			assert.NoError(t, err)
+			assert.NoError(t, ioutil.WriteFile(tempfile.Canonpath(), []byte(content), 0755))
+
+			assert.NoError(t, output.Store(sha.String(), tempfile, time.Now()))
+			assert.True(t, output.Exists(sha.String()))
+
+			children, err := tempdir.Children()
+			assert.NoError(t, err)
+			assert.Len(t, children, 1, "temporary files are removed")
+
+			stored, err := tempdir.Child(sha.String())
+			assert.NoError(t, err)
+			stat, err := stored.Stat()
+			assert.NoError(t, err)
+			assert.Equal(t, SafeFileMode, stat.Mode().Perm())
+
+			storedContent, err := ioutil.ReadFile(stored.Canonpath())
+			assert.NoError(t, err)
+
+			if wrapping == WrapNone {
+				assert.Equal(t, content, string(storedContent))
+				return
+			}
+
+			assert.NotContains(t, string(storedContent), content)
+
+			var unwrapped bytes.Buffer
+			assert.NoError(t, Unwrap(bytes.NewReader(storedContent), &unwrapped, key, sha, AlgorithmSHA256))
+			assert.Equal(t, content, unwrapped.String())
+
+			assert.Error(t, Unwrap(bytes.NewReader(storedContent), ioutil.Discard, []byte("bad key"), sha, AlgorithmSHA256))
+		})
+	}
+
+	_, err = NewSafeDirOutput(os.TempDir(), SafeOpts{Wrapping: WrapAES})
+	assert.Error(t, err, "key is required")
+
+	_, err = NewSafeDirOutput(os.TempDir(), SafeOpts{Wrapping: "rot13", Key: []byte("x")})
+	assert.Error(t, err)
+
+	assert.Error(t, Unwrap(bytes.NewReader([]byte(content)), ioutil.Discard, []byte("x"), sha, AlgorithmSHA256), "not wrapped")
+}
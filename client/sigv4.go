@@ -0,0 +1,446 @@
+package storclient
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRegion is region of SigV4Signer without region
+	DefaultRegion = "us-east-1"
+	// MaxPresignExpires is max validity of pre-signed url
+	MaxPresignExpires = 7 * 24 * time.Hour
+
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+	sigV4Service   = "s3"
+	// hash of content isn't signed (content is verified by sha of object)
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// sha256 of empty content
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	amzDateFormat  = "20060102T150405Z"
+	amzShortFormat = "20060102"
+
+	headerAmzDate          = "X-Amz-Date"
+	headerAmzContentSha256 = "X-Amz-Content-Sha256"
+	headerAmzSecurityToken = "X-Amz-Security-Token"
+)
+
+// Credentials are AWS access keys
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// token of temporary credentials (optional)
+	SessionToken string
+}
+
+// CredentialsProvider returns credentials for signing of requests
+type CredentialsProvider interface {
+	Retrieve() (Credentials, error)
+}
+
+type staticCredentials Credentials
+
+// StaticCredentials returns provider of given keys
+func StaticCredentials(accessKeyID, secretAccessKey, sessionToken string) CredentialsProvider {
+	return staticCredentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey, SessionToken: sessionToken}
+}
+
+func (c staticCredentials) Retrieve() (Credentials, error) {
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return Credentials{}, fmt.Errorf("Access key or secret key is empty")
+	}
+
+	return Credentials(c), nil
+}
+
+type envCredentials struct{}
+
+// EnvCredentials returns provider of keys from environment variables
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+func EnvCredentials() CredentialsProvider {
+	return envCredentials{}
+}
+
+func (envCredentials) Retrieve() (Credentials, error) {
+	credentials := Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return Credentials{}, fmt.Errorf("AWS_ACCESS_KEY_ID or AWS_SECRET_ACCESS_KEY isn't set")
+	}
+
+	return credentials, nil
+}
+
+type sharedCredentials struct {
+	path, profile string
+	once          sync.Once
+	credentials   Credentials
+	err           error
+}
+
+// SharedCredentials returns provider of keys from profile of shared credentials file (INI format of aws cli)
+//
+// empty path is $AWS_SHARED_CREDENTIALS_FILE or ~/.aws/credentials,
+// empty profile is $AWS_PROFILE or default; file is read once (by first Retrieve)
+func SharedCredentials(path, profile string) CredentialsProvider {
+	if path == "" {
+		path = os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	}
+	if path == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, ".aws", "credentials")
+		}
+	}
+
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	return &sharedCredentials{path: path, profile: profile}
+}
+
+func (c *sharedCredentials) Retrieve() (Credentials, error) {
+	c.once.Do(func() {
+		c.credentials, c.err = readSharedCredentials(c.path, c.profile)
+	})
+
+	return c.credentials, c.err
+}
+
+func readSharedCredentials(path, profile string) (Credentials, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer file.Close()
+
+	var credentials Credentials
+	found := false
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			found = found || section == profile
+			continue
+		}
+
+		if section != profile {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		value := strings.TrimSpace(parts[1])
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case "aws_access_key_id":
+			credentials.AccessKeyID = value
+		case "aws_secret_access_key":
+			credentials.SecretAccessKey = value
+		case "aws_session_token":
+			credentials.SessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Credentials{}, err
+	}
+
+	if !found {
+		return Credentials{}, fmt.Errorf("Profile %s isn't in %s", profile, path)
+	}
+
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return Credentials{}, fmt.Errorf("Profile %s in %s hasn't aws_access_key_id or aws_secret_access_key", profile, path)
+	}
+
+	return credentials, nil
+}
+
+type chainCredentials []CredentialsProvider
+
+// ChainCredentials returns provider of credentials of first successful provider,
+// e.g. ChainCredentials(EnvCredentials(), SharedCredentials("", ""))
+func ChainCredentials(providers ...CredentialsProvider) CredentialsProvider {
+	return chainCredentials(providers)
+}
+
+func (providers chainCredentials) Retrieve() (Credentials, error) {
+	errs := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		credentials, err := provider.Retrieve()
+		if err == nil {
+			return credentials, nil
+		}
+
+		errs = append(errs, err.Error())
+	}
+
+	return Credentials{}, fmt.Errorf("No credentials: %s", strings.Join(errs, "; "))
+}
+
+// SigV4Signer is Authenticator signing requests by AWS Signature Version 4
+// (Authorization header), e.g. for private S3 bucket or S3-compatible storage (MinIO, Ceph)
+//
+//	client, err := storclient.New(storageUrl, dir, storclient.StorClientOpts{
+//		S3URL:  s3url,
+//		S3Auth: storclient.NewSigV4Signer(storclient.EnvCredentials(), "eu-central-1"),
+//	})
+type SigV4Signer struct {
+	Credentials CredentialsProvider
+	Region      string
+	// signing name of service, default ("") is s3
+	Service string
+	// clock of signer (tests), default (nil) is time.Now
+	now func() time.Time
+}
+
+// NewSigV4Signer returns signer of requests to S3 in region (DefaultRegion if empty)
+func NewSigV4Signer(credentials CredentialsProvider, region string) *SigV4Signer {
+	if region == "" {
+		region = DefaultRegion
+	}
+
+	return &SigV4Signer{Credentials: credentials, Region: region, Service: sigV4Service}
+}
+
+func (s *SigV4Signer) signingTime() time.Time {
+	if s.now != nil {
+		return s.now().UTC()
+	}
+
+	return time.Now().UTC()
+}
+
+func (s *SigV4Signer) service() string {
+	if s.Service == "" {
+		return sigV4Service
+	}
+
+	return s.Service
+}
+
+// scope returns credential scope of date
+func (s *SigV4Signer) scope(t time.Time) string {
+	return strings.Join([]string{t.Format(amzShortFormat), s.Region, s.service(), "aws4_request"}, "/")
+}
+
+// Authenticate signs req (sets headers X-Amz-Date, X-Amz-Content-Sha256, Authorization and X-Amz-Security-Token of temporary credentials)
+//
+// content of request isn't signed (UNSIGNED-PAYLOAD), signed headers are Host, Content-Type, Content-Md5, Range and X-Amz-*
+func (s *SigV4Signer) Authenticate(req *http.Request) error {
+	credentials, err := s.Credentials.Retrieve()
+	if err != nil {
+		return err
+	}
+
+	t := s.signingTime()
+	req.Header.Set(headerAmzDate, t.Format(amzDateFormat))
+	if credentials.SessionToken != "" {
+		req.Header.Set(headerAmzSecurityToken, credentials.SessionToken)
+	}
+
+	payloadHash := req.Header.Get(headerAmzContentSha256)
+	if payloadHash == "" {
+		payloadHash = unsignedPayload
+		if req.Body == nil || req.Body == http.NoBody {
+			payloadHash = emptyPayloadHash
+		}
+		req.Header.Set(headerAmzContentSha256, payloadHash)
+	}
+
+	headers, signedHeaders := canonicalHeaders(req)
+	canonical := canonicalRequest(req.Method, req.URL.Path, req.URL.Query(), headers, signedHeaders, payloadHash)
+	signature := s.signature(credentials, t, canonical)
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, credentials.AccessKeyID, s.scope(t), signedHeaders, signature))
+
+	return nil
+}
+
+// Presign returns url of req signed by query parameters (X-Amz-Signature, ...) which is valid for expires (max MaxPresignExpires),
+// e.g. for sharing of object with somebody without credentials
+//
+// only host header is signed and content isn't signed (UNSIGNED-PAYLOAD)
+func (s *SigV4Signer) Presign(req *http.Request, expires time.Duration) (string, error) {
+	if expires < time.Second || expires > MaxPresignExpires {
+		return "", fmt.Errorf("Expiration of pre-signed url must be between 1s and %s, not %s", MaxPresignExpires, expires)
+	}
+
+	credentials, err := s.Credentials.Retrieve()
+	if err != nil {
+		return "", err
+	}
+
+	t := s.signingTime()
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", credentials.AccessKeyID+"/"+s.scope(t))
+	query.Set("X-Amz-Date", t.Format(amzDateFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if credentials.SessionToken != "" {
+		query.Set(headerAmzSecurityToken, credentials.SessionToken)
+	}
+
+	canonical := canonicalRequest(req.Method, req.URL.Path, query, "host:"+requestHost(req)+"\n", "host", unsignedPayload)
+
+	u := *req.URL
+	u.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + s.signature(credentials, t, canonical)
+
+	return u.String(), nil
+}
+
+// signature returns hex of signature of canonical request
+func (s *SigV4Signer) signature(credentials Credentials, t time.Time, canonical string) string {
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		t.Format(amzDateFormat),
+		s.scope(t),
+		hexSha256(canonical),
+	}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+credentials.SecretAccessKey), t.Format(amzShortFormat))
+	key = hmacSha256(key, s.Region)
+	key = hmacSha256(key, s.service())
+	key = hmacSha256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSha256(key, stringToSign))
+}
+
+// isSignedHeader returns true for (lower case) name of header which is signed
+func isSignedHeader(name string) bool {
+	switch name {
+	case "host", "content-type", "content-md5", "range":
+		return true
+	}
+
+	return strings.HasPrefix(name, "x-amz-")
+}
+
+// canonicalHeaders returns canonical headers (with trailing new line) and list of signed headers of req
+func canonicalHeaders(req *http.Request) (string, string) {
+	values := map[string]string{}
+	for name, vals := range req.Header {
+		name = strings.ToLower(name)
+		if !isSignedHeader(name) {
+			continue
+		}
+
+		trimmed := make([]string, len(vals))
+		for i, value := range vals {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		values[name] = strings.Join(trimmed, ",")
+	}
+
+	values["host"] = requestHost(req)
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var headers strings.Builder
+	for _, name := range names {
+		headers.WriteString(name + ":" + values[name] + "\n")
+	}
+
+	return headers.String(), strings.Join(names, ";")
+}
+
+// requestHost returns value of Host header of req
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+
+	return req.URL.Host
+}
+
+// canonicalRequest returns canonical request of SigV4
+func canonicalRequest(method, path string, query map[string][]string, headers, signedHeaders, payloadHash string) string {
+	if path == "" {
+		path = "/"
+	}
+
+	return strings.Join([]string{
+		method,
+		uriEncode(path, false),
+		canonicalQuery(query),
+		headers,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+}
+
+// canonicalQuery returns query sorted by keys (and values) with encoded keys and values
+func canonicalQuery(query map[string][]string) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode encodes all bytes except unreserved characters (A-Z, a-z, 0-9, '-', '.', '_', '~') as SigV4 requires,
+// slash is encoded only if encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			encoded.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+
+	return encoded.String()
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSha256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
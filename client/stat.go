@@ -0,0 +1,67 @@
+package storclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/avast/hashutil-go"
+)
+
+// ObjectInfo is metadata of object on storage
+type ObjectInfo struct {
+	Sha hashutil.Hash
+	// size of object, -1 means unknown (storage doesn't return Content-Length)
+	Size int64
+	// last modification time of object (zero if storage doesn't return Last-Modified)
+	LastModified time.Time
+	// url of object (S3 or stor)
+	URL string
+}
+
+// Stat returns metadata of object from HEAD request
+//
+// object is requested same way as in Download (S3 first if is set, stor fallback, retry),
+// missing object is returned as error (see Exists)
+func (client *StorClient) Stat(ctx context.Context, sha hashutil.Hash) (ObjectInfo, error) {
+	info := ObjectInfo{Sha: sha}
+	_, err := client.retryWithFallback(ctx, Fields{fieldSha: sha.String()}, Object{Sha: sha}, nil, func(ctx context.Context, u string) error {
+		resp, err := requestObject(ctx, client.httpClient, http.MethodHead, u, sha)
+		if err != nil {
+			return err
+		}
+
+		info.Size = resp.ContentLength
+		info.URL = u
+		if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+			if info.LastModified, err = http.ParseTime(lastModified); err != nil {
+				client.Logger.Debug(fmt.Sprintf("Invalid Last-Modified %q of %s: %s", lastModified, sha, err), Fields{fieldSha: sha.String()})
+			}
+		}
+
+		return resp.Body.Close()
+	})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return info, nil
+}
+
+// Exists returns true if object is on storage (S3 or stor)
+//
+// error is returned only if existence can't be decided (e.g. network error)
+func (client *StorClient) Exists(ctx context.Context, sha hashutil.Hash) (bool, error) {
+	_, err := client.Stat(ctx, sha)
+	if err == nil {
+		return true, nil
+	}
+
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+
+	return false, err
+}
@@ -0,0 +1,71 @@
+package storclient_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStat(t *testing.T) {
+	lastModified := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+	missingSha, err := hashutil.StringToHash(sha256.New(), "01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b")
+	assert.NoError(t, err)
+
+	s3requests := 0
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s3requests++
+		http.NotFound(w, r)
+	}))
+	defer s3.Close()
+
+	stor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		if r.URL.Path == fmt.Sprintf("/%s", emptySha) {
+			w.Header().Set("Content-Length", "0")
+			w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+			return
+		}
+
+		if r.URL.Path == fmt.Sprintf("/%s", missingSha) {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer stor.Close()
+
+	storURL, _ := url.Parse(stor.URL)
+	s3URL, _ := url.Parse(s3.URL)
+	client, err := storclient.New(*storURL, "some_dir", storclient.StorClientOpts{RetryAttempts: 2, RetryDelay: time.Millisecond, S3URL: s3URL})
+	assert.NoError(t, err)
+
+	info, err := client.Stat(context.Background(), emptySha)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size)
+	assert.True(t, lastModified.Equal(info.LastModified))
+	assert.Equal(t, fmt.Sprintf("%s/%s", stor.URL, emptySha), info.URL, "fallback to stor")
+	assert.Equal(t, 1, s3requests)
+
+	exists, err := client.Exists(context.Background(), emptySha)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = client.Exists(context.Background(), missingSha)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	otherSha, err := hashutil.StringToHash(sha256.New(), "edeaaff3f1774ad2888673770c6d64097e391bc362d7d6fb34982ddf0efd18cb")
+	assert.NoError(t, err)
+	_, err = client.Exists(context.Background(), otherSha)
+	assert.Error(t, err, "server error isn't decision")
+}
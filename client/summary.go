@@ -0,0 +1,136 @@
+package storclient
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// BackendStat is statistic of requests to one backend (s3, stor)
+type BackendStat struct {
+	// size of files downloaded from backend
+	Size int64 `json:"size"`
+	// count of requests (attempts) to backend
+	Requests int `json:"requests"`
+	// count of files which weren't found on backend (or were denied by bucket of S3) and were requested from next backend (S3 -> stor)
+	Fallbacks int `json:"fallbacks"`
+}
+
+// Summary is summary of TotalStat with rate and percentiles, e.g. for JSON output
+type Summary struct {
+	Expected     int                    `json:"expected"`
+	Downloaded   int                    `json:"downloaded"`
+	Skipped      int                    `json:"skipped"`
+	Failed       int                    `json:"failed"`
+	NotFound     int                    `json:"not_found"`
+	HashMismatch int                    `json:"hash_mismatch"`
+	NetworkError int                    `json:"network_error"`
+	ServerError  int                    `json:"server_error"`
+	Retries      int                    `json:"retries"`
+	Size         int64                  `json:"size"`
+	Elapsed      float64                `json:"elapsed_seconds"`
+	Rate         float64                `json:"rate_bytes_per_second"`
+	P50          float64                `json:"p50_seconds"`
+	P95          float64                `json:"p95_seconds"`
+	P99          float64                `json:"p99_seconds"`
+	Backends     map[string]BackendStat `json:"backends,omitempty"`
+}
+
+// Summary returns summary of total stats, elapsed is wall time of downloads (for rate)
+func (total TotalStat) Summary(elapsed time.Duration) Summary {
+	summary := Summary{
+		Expected:     total.expectedDownloadCount,
+		Downloaded:   total.Count,
+		Skipped:      total.Skip,
+		Failed:       total.Fail,
+		NotFound:     total.NotFound,
+		HashMismatch: total.HashMismatch,
+		NetworkError: total.NetworkError,
+		ServerError:  total.ServerError,
+		Retries:      total.Retries,
+		Size:         total.Size,
+		Elapsed:      elapsed.Seconds(),
+		P50:          total.Percentile(50).Seconds(),
+		P95:          total.Percentile(95).Seconds(),
+		P99:          total.Percentile(99).Seconds(),
+		Backends:     total.Backends,
+	}
+
+	if elapsed > 0 {
+		summary.Rate = float64(total.Size) / elapsed.Seconds()
+	}
+
+	return summary
+}
+
+// Percentile returns p-th percentile (0-100) of durations of downloaded files (nearest-rank),
+// zero if nothing is downloaded
+func (total TotalStat) Percentile(p float64) time.Duration {
+	if len(total.durations) == 0 {
+		return 0
+	}
+
+	durations := make([]time.Duration, len(total.durations))
+	copy(durations, total.durations)
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(durations))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(durations) {
+		rank = len(durations)
+	}
+
+	return durations[rank-1]
+}
+
+// addRequests adds requests of download to per backend statistics and retries
+func (total *TotalStat) addRequests(stat DownStat) {
+	requests := 0
+	for backend, count := range stat.Requests {
+		backendStat := total.backend(backend)
+		backendStat.Requests += count
+		total.Backends[backend] = backendStat
+		requests += count
+	}
+
+	if requests > 1 {
+		total.Retries += requests - 1
+	}
+
+	if stat.Fallback {
+		backendStat := total.backend(backendS3)
+		backendStat.Fallbacks++
+		total.Backends[backendS3] = backendStat
+	}
+
+	if stat.Status == DOWN_OK && stat.Backend != "" {
+		backendStat := total.backend(stat.Backend)
+		backendStat.Size += stat.Size
+		total.Backends[stat.Backend] = backendStat
+	}
+}
+
+func (total *TotalStat) backend(name string) BackendStat {
+	if total.Backends == nil {
+		total.Backends = make(map[string]BackendStat)
+	}
+
+	return total.Backends[name]
+}
+
+// clone returns copy of total which doesn't share backends and durations with original
+func (total TotalStat) clone() TotalStat {
+	if total.Backends != nil {
+		backends := make(map[string]BackendStat, len(total.Backends))
+		for name, stat := range total.Backends {
+			backends[name] = stat
+		}
+		total.Backends = backends
+	}
+
+	total.durations = append([]time.Duration(nil), total.durations...)
+
+	return total
+}
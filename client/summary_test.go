@@ -0,0 +1,109 @@
+package storclient
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTotalStatDetails(t *testing.T) {
+	missingSha := testHash(t, "01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b")
+	mismatchSha := testHash(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	brokenSha := testHash(t, "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7")
+	unavailableSha := testHash(t, "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c")
+	forbiddenSha := testHash(t, "7d865e959b2466918c9863afca942d0fb89d7c9ac0c99bafc3749504ded97730")
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer s3.Close()
+
+	stor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/%s", missingSha):
+			http.NotFound(w, r)
+		case fmt.Sprintf("/%s", mismatchSha):
+			fmt.Fprint(w, "bye")
+		case fmt.Sprintf("/%s", brokenSha):
+			// promised content is longer than sent one
+			w.Header().Set("Content-Length", "10")
+			fmt.Fprint(w, "wor")
+		case fmt.Sprintf("/%s", unavailableSha):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case fmt.Sprintf("/%s", forbiddenSha):
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer stor.Close()
+
+	storURL, _ := url.Parse(stor.URL)
+	s3URL, _ := url.Parse(s3.URL)
+	client, err := New(*storURL, "some_dir", StorClientOpts{Devnull: true, Max: 1, RetryAttempts: 2, RetryDelay: time.Millisecond, S3URL: s3URL})
+	assert.NoError(t, err)
+
+	client.Start()
+	defer func() {
+		assert.NoError(t, client.Close())
+	}()
+
+	for _, sha := range []hashutil.Hash{emptyHash, missingSha, mismatchSha, brokenSha, unavailableSha, forbiddenSha} {
+		client.Download(sha)
+	}
+	total := client.Wait()
+
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, 5, total.Fail)
+	assert.Equal(t, 1, total.NotFound)
+	assert.Equal(t, 1, total.HashMismatch)
+	assert.Equal(t, 1, total.NetworkError)
+	assert.Equal(t, 1, total.ServerError, "403 isn't server error")
+	assert.Equal(t, 6, total.Retries, "every sha falls back from S3 to stor (2 attempts)")
+	assert.Equal(t, map[string]BackendStat{
+		backendS3:   {Requests: 6, Fallbacks: 6},
+		backendStor: {Requests: 6},
+	}, total.Backends)
+
+	summary := total.Summary(time.Second)
+	assert.Equal(t, 6, summary.Expected)
+	assert.Equal(t, total.Percentile(50).Seconds(), summary.P50)
+
+	data, err := json.Marshal(summary)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"not_found":1`)
+	assert.Contains(t, string(data), `"server_error":1`)
+	assert.Contains(t, string(data), `"s3":{"size":0,"requests":6,"fallbacks":6}`)
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, time.Duration(0), TotalStat{}.Percentile(50))
+
+	var total TotalStat
+	for i := 100; i >= 1; i-- {
+		total.add(DownStat{Status: DOWN_OK, Duration: time.Duration(i) * time.Millisecond})
+	}
+
+	assert.Equal(t, 50*time.Millisecond, total.Percentile(50))
+	assert.Equal(t, 95*time.Millisecond, total.Percentile(95))
+	assert.Equal(t, 99*time.Millisecond, total.Percentile(99))
+	assert.Equal(t, 1*time.Millisecond, total.Percentile(0))
+	assert.Equal(t, 100*time.Millisecond, total.Percentile(100))
+
+	clone := total.clone()
+	clone.durations[0] = 0
+	assert.Equal(t, 100*time.Millisecond, total.durations[0], "clone doesn't share durations")
+}
+
+func testHash(t *testing.T, hexSha string) hashutil.Hash {
+	sha, err := hashutil.StringToHash(sha256.New(), hexSha)
+	assert.NoError(t, err)
+
+	return sha
+}
@@ -0,0 +1,90 @@
+package storclient
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// name of tracer (instrumentation scope) of client spans
+const tracerName = "github.com/avast/stor-client/client"
+
+// names of spans
+//
+// download of object from Download (stor.download) has children
+// stor.queue (wait for free worker) and stor.attempt (every attempt of retry),
+// attempt has children stor.request (request to S3 or stor until response headers),
+// stor.transfer (read of body) and stor.verify (hash verification)
+const (
+	spanDownload = "stor.download"
+	spanQueue    = "stor.queue"
+	spanAttempt  = "stor.attempt"
+	spanRequest  = "stor.request"
+	spanTransfer = "stor.transfer"
+	spanVerify   = "stor.verify"
+)
+
+// attributes of spans
+const (
+	attrSha     = attribute.Key("stor.sha")
+	attrBackend = attribute.Key("stor.backend")
+	attrAttempt = attribute.Key("stor.attempt")
+	attrStatus  = attribute.Key("stor.status")
+	attrSize    = attribute.Key("stor.size")
+	attrMethod  = attribute.Key("http.request.method")
+	attrURL     = attribute.Key("url.full")
+	attrCode    = attribute.Key("http.response.status_code")
+)
+
+func (client *StorClient) tracer() trace.Tracer {
+	return client.TracerProvider.Tracer(tracerName)
+}
+
+// startSpan starts child span of span in ctx by its tracer provider (noop if ctx hasn't span)
+func startSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// endSpan records err (if is set) to span and ends span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// tracingHTTPClient traces requests (stor.request span) and propagates trace context in request headers
+type tracingHTTPClient struct {
+	httpClient httpClient
+	propagator propagation.TextMapPropagator
+}
+
+func (c tracingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	ctx, span := startSpan(req.Context(), spanRequest,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrMethod.String(req.Method), attrURL.String(req.URL.String())),
+	)
+
+	req = req.WithContext(ctx)
+	c.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(attrCode.Int(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	span.End()
+
+	return resp, nil
+}
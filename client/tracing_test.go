@@ -0,0 +1,108 @@
+package storclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	var traceparents []string
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("Traceparent"))
+		http.NotFound(w, r)
+	}))
+	defer s3.Close()
+
+	stor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("Traceparent"))
+	}))
+	defer stor.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	storURL, _ := url.Parse(stor.URL)
+	s3URL, _ := url.Parse(s3.URL)
+	client, err := New(*storURL, "some_dir", StorClientOpts{
+		Devnull:        true,
+		RetryDelay:     time.Millisecond,
+		S3URL:          s3URL,
+		TracerProvider: provider,
+		Propagator:     propagation.TraceContext{},
+	})
+	assert.NoError(t, err)
+
+	client.Start()
+	client.Download(emptyHash)
+	assert.True(t, client.Wait().Status())
+	assert.NoError(t, client.Close())
+
+	spans := map[string]tracetest.SpanStubs{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = append(spans[span.Name], span)
+	}
+
+	if !assert.Len(t, spans[spanDownload], 1) {
+		return
+	}
+	download := spans[spanDownload][0]
+	assert.Equal(t, emptyHash.String(), spanAttribute(download, attrSha).AsString())
+	assert.Equal(t, "ok", spanAttribute(download, attrStatus).AsString())
+
+	assert.Len(t, spans[spanQueue], 1)
+	assert.Equal(t, download.SpanContext.SpanID(), spans[spanQueue][0].Parent.SpanID())
+
+	attempts := spans[spanAttempt]
+	if !assert.Len(t, attempts, 2) {
+		return
+	}
+	assert.Equal(t, backendS3, spanAttribute(attempts[0], attrBackend).AsString())
+	assert.Equal(t, codes.Error, attempts[0].Status.Code, "404 of S3")
+	assert.Equal(t, backendStor, spanAttribute(attempts[1], attrBackend).AsString())
+	for _, attempt := range attempts {
+		assert.Equal(t, download.SpanContext.SpanID(), attempt.Parent.SpanID())
+	}
+
+	requests := spans[spanRequest]
+	if !assert.Len(t, requests, 2) {
+		return
+	}
+	assert.Equal(t, attempts[0].SpanContext.SpanID(), requests[0].Parent.SpanID())
+	assert.Equal(t, int64(404), spanAttribute(requests[0], attrCode).AsInt64())
+	assert.Equal(t, attempts[1].SpanContext.SpanID(), requests[1].Parent.SpanID())
+
+	for _, name := range []string{spanTransfer, spanVerify} {
+		if assert.Len(t, spans[name], 1, name) {
+			assert.Equal(t, attempts[1].SpanContext.SpanID(), spans[name][0].Parent.SpanID(), name)
+		}
+	}
+
+	if assert.Len(t, traceparents, 2) {
+		for i, traceparent := range traceparents {
+			assert.True(t, strings.Contains(traceparent, download.SpanContext.TraceID().String()), "trace id is propagated")
+			assert.True(t, strings.Contains(traceparent, requests[i].SpanContext.SpanID().String()), "parent is request span")
+		}
+	}
+}
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+
+	return attribute.Value{}
+}
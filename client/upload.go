@@ -0,0 +1,116 @@
+package storclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/retry-go"
+)
+
+// Put uploads content as object sha to storage (PUT request to stor url of object, S3 isn't used)
+//
+// content isn't verified (see Upload) and it is read from start by every attempt of retry,
+// storage must support upload
+func (client *StorClient) Put(ctx context.Context, sha hashutil.Hash, content io.ReadSeeker) error {
+	fields := Fields{fieldSha: sha.String(), fieldBackend: backendStor}
+
+	err := retry.Do(
+		func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			u, err := client.createStorURL(sha)
+			if err != nil {
+				return err
+			}
+
+			return putObject(withBackend(ctx, backendStor), client.httpClient, u, sha, content)
+		},
+		retry.OnRetry(func(n uint, err error) {
+			client.Logger.Debug(fmt.Sprintf("Retry #%d: %s", n, err), fields.with(fieldAttempt, n+1))
+		}),
+		retry.RetryIf(func(err error) bool {
+			if ctx.Err() != nil {
+				return false
+			}
+
+			// client errors (4xx) are permanent
+			var statusErr *HTTPStatusError
+			if errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 {
+				return false
+			}
+
+			return true
+		}),
+		retry.Delay(client.RetryDelay),
+		retry.Attempts(client.RetryAttempts),
+		retry.Units(1),
+	)
+
+	return retryResult(ctx, err)
+}
+
+// Upload computes hash (Algorithm) of file at path and uploads it to storage (see Put)
+func (client *StorClient) Upload(ctx context.Context, path string) (hashutil.Hash, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return hashutil.Hash{}, err
+	}
+	defer file.Close()
+
+	hasher := client.Algorithm.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return hashutil.Hash{}, err
+	}
+
+	sha, err := hashutil.BytesToHash(hasher, hasher.Sum(nil))
+	if err != nil {
+		return hashutil.Hash{}, err
+	}
+
+	if err := client.Put(ctx, sha, file); err != nil {
+		return hashutil.Hash{}, err
+	}
+
+	return sha, nil
+}
+
+func putObject(ctx context.Context, httpClient httpClient, url string, sha hashutil.Hash, content io.ReadSeeker) error {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, url, ioutil.NopCloser(content))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	default:
+		return &HTTPStatusError{Sha: sha, Method: http.MethodPut, URL: url, Backend: backendStor, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+}
@@ -0,0 +1,71 @@
+package storclient_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpload(t *testing.T) {
+	uploaded := make(map[string]string)
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.URL.Path == "/forbidden" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		uploaded[r.URL.Path] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "some_dir", storclient.StorClientOpts{RetryAttempts: 3, RetryDelay: time.Millisecond})
+	assert.NoError(t, err)
+
+	file, err := ioutil.TempFile("", "upload")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.Remove(file.Name()))
+	}()
+	_, err = file.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	sha, err := client.Upload(context.Background(), file.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", sha.String())
+	assert.Equal(t, "hello", uploaded[fmt.Sprintf("/%s", sha)], "uploaded by second attempt")
+
+	forbiddenClient, err := storclient.New(*serverURL, "some_dir", storclient.StorClientOpts{RetryAttempts: 3, RetryDelay: time.Millisecond, StorTemplate: "forbidden"})
+	assert.NoError(t, err)
+
+	attempts = 1
+	emptySha, err := hashutil.StringToHash(sha256.New(), "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	assert.NoError(t, err)
+	assert.Error(t, forbiddenClient.Put(context.Background(), emptySha, strings.NewReader("")))
+	assert.Equal(t, 2, attempts, "4xx isn't retried")
+}
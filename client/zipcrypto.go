@@ -0,0 +1,78 @@
+package storclient
+
+import (
+	"hash/crc32"
+	"io"
+)
+
+// zipCrypto is traditional PKWARE zip encryption (ZipCrypto)
+//
+// it is weak, but it is de facto standard for sharing of malware samples
+// (e.g. password "infected") and it is supported by all zip tools
+type zipCrypto struct {
+	keys [3]uint32
+}
+
+const zipCryptoHeaderLen = 12
+
+func newZipCrypto(password string) *zipCrypto {
+	z := &zipCrypto{keys: [3]uint32{0x12345678, 0x23456789, 0x34567890}}
+	for i := 0; i < len(password); i++ {
+		z.updateKeys(password[i])
+	}
+
+	return z
+}
+
+func (z *zipCrypto) updateKeys(b byte) {
+	z.keys[0] = crc32Update(z.keys[0], b)
+	z.keys[1] = (z.keys[1]+(z.keys[0]&0xff))*134775813 + 1
+	z.keys[2] = crc32Update(z.keys[2], byte(z.keys[1]>>24))
+}
+
+func (z *zipCrypto) streamByte() byte {
+	temp := uint16(z.keys[2] | 2)
+	return byte((uint32(temp) * uint32(temp^1)) >> 8)
+}
+
+func (z *zipCrypto) encrypt(p []byte) {
+	for i, b := range p {
+		p[i] = b ^ z.streamByte()
+		z.updateKeys(b)
+	}
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[(crc^uint32(b))&0xff] ^ (crc >> 8)
+}
+
+type zipCryptoWriter struct {
+	w      io.Writer
+	crypto *zipCrypto
+	buf    []byte
+}
+
+// newZipCryptoWriter returns writer which encrypts data to w,
+// encryption header (12 bytes) with check byte of crc32 is written first
+func newZipCryptoWriter(w io.Writer, password string, header [zipCryptoHeaderLen]byte, crc uint32) (io.Writer, error) {
+	zw := &zipCryptoWriter{w: w, crypto: newZipCrypto(password)}
+
+	header[zipCryptoHeaderLen-1] = byte(crc >> 24)
+	if _, err := zw.Write(header[:]); err != nil {
+		return nil, err
+	}
+
+	return zw, nil
+}
+
+func (zw *zipCryptoWriter) Write(p []byte) (int, error) {
+	if cap(zw.buf) < len(p) {
+		zw.buf = make([]byte, len(p))
+	}
+	buf := zw.buf[:len(p)]
+	copy(buf, p)
+
+	zw.crypto.encrypt(buf)
+
+	return zw.w.Write(buf)
+}
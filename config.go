@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kingpin"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// prefix of environment variables overriding flags, e.g. STOR_CLIENT_STORAGE for --storage
+const envPrefix = "STOR_CLIENT_"
+
+// name of default config file in user config directory
+const configFileName = "stor-client.yaml"
+
+// config is configuration file with named profiles
+//
+// profile is map of (long) flag names to values, flags of command are in map under name of command, e.g.
+//
+//	profile: prod
+//	profiles:
+//	  prod:
+//	    storage: http://stor.domain.tld
+//	    s3host: https://bucket.s3.eu-central-1.amazonaws.com
+//	    max: 8
+//	    mirror: {to: http://stor.backup.domain.tld}
+//	  lab:
+//	    storage: http://stor.lab.domain.tld
+//	    column: {sha256: hash}
+type config struct {
+	// profile used if --profile isn't set
+	Profile  string                            `yaml:"profile"`
+	Profiles map[string]map[string]interface{} `yaml:"profiles"`
+}
+
+// defaultConfigPath returns path of config file in user config directory ($XDG_CONFIG_HOME or ~/.config)
+func defaultConfigPath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, configFileName)
+	}
+
+	return filepath.Join(os.Getenv("HOME"), ".config", configFileName)
+}
+
+func loadConfig(path string) (*config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("Parse of config %s fail: %s", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// setEnvars sets environment variable (envPrefix + flag name) to all flags of app
+// and environment variable (envPrefix + command + flag name, see commandFlagEnvar) to flags of commands
+func setEnvars(app *kingpin.Application) {
+	model := app.Model()
+	for _, flag := range model.Flags {
+		if flag.Hidden || flag.Name == "help" || flag.Name == "version" {
+			continue
+		}
+
+		app.GetFlag(flag.Name).Envar(flagEnvar(flag.Name))
+	}
+
+	for _, command := range model.Commands {
+		for _, flag := range command.Flags {
+			if flag.Hidden {
+				continue
+			}
+
+			app.GetCommand(command.Name).GetFlag(flag.Name).Envar(commandFlagEnvar(command.Name, flag.Name))
+		}
+	}
+}
+
+func flagEnvar(name string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// commandFlagEnvar returns environment variable of flag of command, e.g. STOR_CLIENT_MIRROR_TO for mirror --to
+func commandFlagEnvar(command, name string) string {
+	return flagEnvar(command + "-" + name)
+}
+
+// applyConfig sets values of profile from config as defaults of flags of app
+//
+// config and profile are read from args (--config, --profile) or environment before parsing,
+// missing default config file is ignored
+func applyConfig(app *kingpin.Application, args []string) error {
+	path := preparseFlag(args, "config")
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+
+	profile := preparseFlag(args, "profile")
+
+	if _, err := os.Stat(path); os.IsNotExist(err) && !explicit {
+		if profile != "" {
+			return fmt.Errorf("Profile %s is set, but config %s doesn't exist", profile, path)
+		}
+
+		return nil
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	if profile == "" {
+		profile = cfg.Profile
+	}
+
+	if profile == "" {
+		return nil
+	}
+
+	return cfg.apply(app, profile)
+}
+
+// apply sets values of profile as defaults of flags of app
+func (cfg *config) apply(app *kingpin.Application, profile string) error {
+	values, ok := cfg.Profiles[profile]
+	if !ok {
+		return fmt.Errorf("Profile %s isn't in config", profile)
+	}
+
+	for name, value := range values {
+		if name == "config" || name == "profile" {
+			return fmt.Errorf("Unknown option %s in profile %s", name, profile)
+		}
+
+		if flag := app.GetFlag(name); flag != nil {
+			if err := setFlagDefault(flag, value); err != nil {
+				return fmt.Errorf("Invalid value of %s in profile %s: %s", name, profile, err)
+			}
+			continue
+		}
+
+		command := app.GetCommand(name)
+		commandValues, ok := value.(map[interface{}]interface{})
+		if command == nil || !ok {
+			return fmt.Errorf("Unknown option %s in profile %s", name, profile)
+		}
+
+		for key, commandValue := range commandValues {
+			flagName := fmt.Sprint(key)
+			flag := command.GetFlag(flagName)
+			if flag == nil {
+				return fmt.Errorf("Unknown option %s of command %s in profile %s", flagName, name, profile)
+			}
+
+			if err := setFlagDefault(flag, commandValue); err != nil {
+				return fmt.Errorf("Invalid value of %s of command %s in profile %s: %s", flagName, name, profile, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// setFlagDefault sets value from config as default of flag
+func setFlagDefault(flag *kingpin.FlagClause, value interface{}) error {
+	defaults, err := flagValues(value)
+	if err != nil {
+		return err
+	}
+
+	flag.Default(defaults...)
+	return nil
+}
+
+// flagValues converts value from config to values of flag,
+// list is repeated flag and map is flag with KEY=VALUE values (e.g. --column)
+func flagValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			itemValues, err := flagValues(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, itemValues...)
+		}
+
+		return values, nil
+	case map[interface{}]interface{}:
+		values := make([]string, 0, len(v))
+		for key, item := range v {
+			values = append(values, fmt.Sprintf("%v=%v", key, item))
+		}
+		sort.Strings(values)
+
+		return values, nil
+	case nil:
+		return nil, fmt.Errorf("value is empty")
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
+
+// preparseFlag returns value of long flag from args or from its environment variable
+func preparseFlag(args []string, name string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		if strings.HasPrefix(arg, "--"+name+"=") {
+			return strings.TrimPrefix(arg, "--"+name+"=")
+		}
+
+		if arg == "--"+name && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	return os.Getenv(flagEnvar(name))
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfig = `
+profile: prod
+profiles:
+  prod:
+    storage: http://stor.prod
+    max: 8
+    input: [a.txt, b.txt]
+    column: {sha256: hash, name: file}
+    mirror: {to: http://stor.backup, to-header: {x-api-key: key}}
+  lab:
+    storage: http://stor.lab
+  broken:
+    unknown: 1
+  broken-command:
+    mirror: {unknown: 1}
+`
+
+type testFlags struct {
+	storage  *string
+	max      *int
+	inputs   *[]string
+	columns  *map[string]string
+	to       *string
+	toHeader *map[string]string
+}
+
+func newTestApp() (*kingpin.Application, testFlags) {
+	app := kingpin.New("test", "")
+	app.Flag("config", "").String()
+	app.Flag("profile", "").String()
+
+	flags := testFlags{
+		storage: app.Flag("storage", "").String(),
+		max:     app.Flag("max", "").Default("4").Int(),
+		inputs:  app.Flag("input", "").Strings(),
+		columns: app.Flag("column", "").StringMap(),
+	}
+	mirror := app.Command("mirror", "")
+	flags.to = mirror.Flag("to", "").String()
+	flags.toHeader = mirror.Flag("to-header", "").StringMap()
+	app.Command("download", "").Default()
+	setEnvars(app)
+
+	return app, flags
+}
+
+func TestApplyConfig(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	configPath := filepath.Join(tempdir, "config.yaml")
+	assert.NoError(t, ioutil.WriteFile(configPath, []byte(testConfig), 0644))
+
+	t.Run("default profile", func(t *testing.T) {
+		app, flags := newTestApp()
+		args := []string{"--config", configPath, "--max=2"}
+		assert.NoError(t, applyConfig(app, args))
+		_, err := app.Parse(args)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "http://stor.prod", *flags.storage)
+		assert.Equal(t, 2, *flags.max, "flag has priority over profile")
+		assert.Equal(t, []string{"a.txt", "b.txt"}, *flags.inputs)
+		assert.Equal(t, map[string]string{"sha256": "hash", "name": "file"}, *flags.columns)
+		assert.Equal(t, "", *flags.to, "flags of other command aren't parsed")
+	})
+
+	t.Run("flags of command", func(t *testing.T) {
+		app, flags := newTestApp()
+		args := []string{"--config", configPath, "mirror"}
+		assert.NoError(t, applyConfig(app, args))
+		_, err := app.Parse(args)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "http://stor.backup", *flags.to)
+		assert.Equal(t, map[string]string{"x-api-key": "key"}, *flags.toHeader)
+
+		assert.NoError(t, os.Setenv("STOR_CLIENT_MIRROR_TO", "http://stor.env"))
+		defer func() {
+			assert.NoError(t, os.Unsetenv("STOR_CLIENT_MIRROR_TO"))
+		}()
+
+		app, flags = newTestApp()
+		assert.NoError(t, applyConfig(app, args))
+		_, err = app.Parse(args)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "http://stor.env", *flags.to, "env has priority over profile")
+	})
+
+	t.Run("env", func(t *testing.T) {
+		assert.NoError(t, os.Setenv("STOR_CLIENT_STORAGE", "http://stor.env"))
+		assert.NoError(t, os.Setenv("STOR_CLIENT_PROFILE", "lab"))
+		defer func() {
+			assert.NoError(t, os.Unsetenv("STOR_CLIENT_STORAGE"))
+			assert.NoError(t, os.Unsetenv("STOR_CLIENT_PROFILE"))
+		}()
+
+		app, flags := newTestApp()
+		args := []string{"--config=" + configPath}
+		assert.NoError(t, applyConfig(app, args))
+		_, err := app.Parse(args)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "http://stor.env", *flags.storage, "env has priority over profile")
+		assert.Equal(t, 4, *flags.max, "default of flag, lab profile hasn't max")
+	})
+
+	t.Run("selected profile", func(t *testing.T) {
+		app, flags := newTestApp()
+		args := []string{"--config", configPath, "--profile", "lab"}
+		assert.NoError(t, applyConfig(app, args))
+		_, err := app.Parse(args)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "http://stor.lab", *flags.storage)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		app, _ := newTestApp()
+		assert.Error(t, applyConfig(app, []string{"--config", configPath, "--profile", "broken"}), "unknown option")
+		assert.Error(t, applyConfig(app, []string{"--config", configPath, "--profile", "broken-command"}), "unknown option of command")
+		assert.Error(t, applyConfig(app, []string{"--config", configPath, "--profile", "missing"}), "missing profile")
+		assert.Error(t, applyConfig(app, []string{"--config", filepath.Join(tempdir, "missing.yaml")}), "missing config")
+	})
+
+	t.Run("without default config", func(t *testing.T) {
+		assert.NoError(t, os.Setenv("XDG_CONFIG_HOME", tempdir))
+		defer func() {
+			assert.NoError(t, os.Unsetenv("XDG_CONFIG_HOME"))
+		}()
+
+		app, _ := newTestApp()
+		assert.NoError(t, applyConfig(app, []string{}))
+		assert.Error(t, applyConfig(app, []string{"--profile=lab"}), "profile without config")
+	})
+}
+
+func TestCommandFlagEnvar(t *testing.T) {
+	assert.Equal(t, "STOR_CLIENT_MIRROR_TO_TOKEN", commandFlagEnvar("mirror", "to-token"))
+	assert.Equal(t, "STOR_CLIENT_GC_OLDER_THAN", commandFlagEnvar("gc", "older-than"))
+}
+
+func TestPreparseFlag(t *testing.T) {
+	assert.Equal(t, "a", preparseFlag([]string{"--profile", "a"}, "profile"))
+	assert.Equal(t, "b", preparseFlag([]string{"-v", "--profile=b", "dir"}, "profile"))
+	assert.Equal(t, "", preparseFlag([]string{"--", "--profile=c"}, "profile"))
+	assert.Equal(t, "", preparseFlag([]string{"--profile"}, "profile"))
+}
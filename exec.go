@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/avast/stor-client/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// executor runs post-download command (--exec) for every downloaded or skipped file
+//
+// commands run in own pool (--exec-max) fed by hooks of client,
+// record of file is written to manifest after its command (with exit code)
+//
+// command of downloaded file runs with its DOWN_OK, command of file skipped because it exists
+// runs after all downloads (Wait) if the file isn't downloaded in this run
+// (file downloaded by other worker can exist before its DOWN_OK is processed)
+type executor struct {
+	command   string
+	dir       string
+	max       int
+	delete    bool
+	manifest  *storclient.Manifest
+	algorithm storclient.Algorithm
+	stats     chan storclient.DownStat
+	wg        sync.WaitGroup
+	lock      sync.Mutex
+	// paths of files with command (file can be in input more times)
+	seen map[string]bool
+	// skipped existing files, their commands run in Wait
+	skipped []storclient.DownStat
+	failed  int
+}
+
+// newExecutor starts pool of max runners of command on files in dir
+func newExecutor(command string, dir string, max int, delete bool, manifest *storclient.Manifest, algorithm storclient.Algorithm) *executor {
+	e := &executor{
+		command:   command,
+		dir:       dir,
+		max:       max,
+		delete:    delete,
+		manifest:  manifest,
+		algorithm: algorithm,
+		stats:     make(chan storclient.DownStat, max),
+		seen:      make(map[string]bool),
+	}
+
+	e.start(e.stats, e.process)
+
+	return e
+}
+
+// start starts max runners of process of stats
+func (e *executor) start(stats <-chan storclient.DownStat, process func(storclient.DownStat)) {
+	for i := 0; i < e.max; i++ {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+
+			for stat := range stats {
+				process(stat)
+			}
+		}()
+	}
+}
+
+// hooks returns hooks of client which send finished downloads to executor
+func (e *executor) hooks() *storclient.Hooks {
+	send := func(stat storclient.DownStat) {
+		e.stats <- stat
+	}
+
+	return &storclient.Hooks{OnComplete: send, OnSkip: send}
+}
+
+// Wait waits to all commands (client must be finished) and returns count of failed commands
+func (e *executor) Wait() int {
+	close(e.stats)
+	e.wg.Wait()
+
+	// all DOWN_OK are processed, commands of skipped files run only if they aren't downloaded in this run
+	skipped := make(chan storclient.DownStat, len(e.skipped))
+	for _, stat := range e.skipped {
+		skipped <- stat
+	}
+	close(skipped)
+
+	e.start(skipped, func(stat storclient.DownStat) {
+		path := e.path(stat)
+		e.finish(stat, path, e.claim(path))
+	})
+	e.wg.Wait()
+
+	return e.failed
+}
+
+func (e *executor) path(stat storclient.DownStat) string {
+	return filepath.Join(e.dir, filepath.FromSlash(stat.Path))
+}
+
+func (e *executor) process(stat storclient.DownStat) {
+	path := e.path(stat)
+
+	run := false
+	switch stat.Status {
+	case storclient.DOWN_OK:
+		run = e.claim(path)
+	case storclient.DOWN_SKIP:
+		// existing file is postponed to Wait (other worker can download it and its DOWN_OK isn't processed yet),
+		// file skipped because it is downloading by other worker doesn't exist yet, its command runs with its DOWN_OK
+		if _, err := os.Stat(path); err == nil {
+			e.lock.Lock()
+			e.skipped = append(e.skipped, stat)
+			e.lock.Unlock()
+			return
+		}
+	}
+
+	e.finish(stat, path, run)
+}
+
+// finish runs command of file (if run is set) and writes record of file to manifest
+func (e *executor) finish(stat storclient.DownStat, path string, run bool) {
+	entry := storclient.NewManifestEntry(stat)
+	if e.algorithm != storclient.DefaultAlgorithm {
+		entry.Algorithm = string(e.algorithm)
+	}
+
+	if run {
+		exitCode := e.run(stat, path)
+		entry.ExitCode = &exitCode
+
+		if exitCode == 0 && e.delete {
+			if err := os.Remove(path); err != nil {
+				log.Errorf("Delete of %s fail: %s", path, err)
+			} else {
+				entry.Deleted = true
+			}
+		}
+	}
+
+	if e.manifest != nil {
+		if err := e.manifest.WriteEntry(entry); err != nil {
+			log.Errorf("Write to manifest fail: %s", err)
+		}
+	}
+}
+
+// claim returns true if file hasn't command yet (only first claim of path runs command)
+func (e *executor) claim(path string) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.seen[path] {
+		return false
+	}
+	e.seen[path] = true
+
+	return true
+}
+
+// run runs command of file by shell and returns its exit code (-1 if command can't be started)
+func (e *executor) run(stat storclient.DownStat, path string) int {
+	cmd := exec.Command("sh", "-c", expandCommand(e.command, stat, path))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	fields := log.Fields{"sha256": stat.Object.Sha.String(), "path": path}
+	err := cmd.Run()
+	if err == nil {
+		log.WithFields(fields).Debug("Command success")
+		return 0
+	}
+
+	exitCode := -1
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	}
+
+	log.WithFields(fields).Errorf("Command fail: %s", err)
+
+	e.lock.Lock()
+	e.failed++
+	e.lock.Unlock()
+
+	return exitCode
+}
+
+// expandCommand replaces placeholders {path}, {sha} and {name} in command by shell quoted values
+func expandCommand(command string, stat storclient.DownStat, path string) string {
+	return strings.NewReplacer(
+		"{path}", shellQuote(path),
+		"{sha}", shellQuote(stat.Object.Sha.String()),
+		"{name}", shellQuote(stat.Object.Name),
+	).Replace(command)
+}
+
+// shellQuote returns s in single quotes (safe for sh even with quotes or spaces in s)
+func shellQuote(s string) string {
+	return fmt.Sprintf("'%s'", strings.Replace(s, "'", `'\''`, -1))
+}
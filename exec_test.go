@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "'file'", shellQuote("file"))
+	assert.Equal(t, `'it'\''s a file'`, shellQuote("it's a file"))
+}
+
+func TestExpandCommand(t *testing.T) {
+	sha, err := hashutil.StringToHash(sha256.New(), testSha1)
+	assert.NoError(t, err)
+
+	stat := storclient.DownStat{Object: storclient.Object{Sha: sha, Name: "my sample.exe"}}
+	assert.Equal(t,
+		"scan '/tmp/dir/"+testSha1+"' --sha='"+testSha1+"' --name='my sample.exe'",
+		expandCommand("scan {path} --sha={sha} --name={name}", stat, "/tmp/dir/"+testSha1),
+	)
+}
+
+func readManifest(t *testing.T, data []byte) map[string]storclient.ManifestEntry {
+	entries := make(map[string]storclient.ManifestEntry)
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var entry storclient.ManifestEntry
+		assert.NoError(t, decoder.Decode(&entry))
+		entries[entry.Path+" "+entry.Status] = entry
+	}
+
+	return entries
+}
+
+func TestExecutor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("--exec isn't supported on windows")
+	}
+
+	tempdir, err := ioutil.TempDir("", "exec")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	for _, sha := range []string{testSha1, testSha2} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, sha), []byte(sha), 0644))
+	}
+
+	stats := []storclient.DownStat{
+		{Status: storclient.DOWN_OK, Path: testSha1},
+		{Status: storclient.DOWN_SKIP, Path: testSha2},
+		// same file again - command runs only once
+		{Status: storclient.DOWN_SKIP, Path: testSha1},
+		{Status: storclient.DOWN_FAIL, Path: "failed"},
+	}
+
+	t.Run("delete after success", func(t *testing.T) {
+		var manifest bytes.Buffer
+		e := newExecutor("test -f {path}", tempdir, 2, true, storclient.NewManifest(&manifest), storclient.DefaultAlgorithm)
+		hooks := e.hooks()
+		for _, stat := range stats {
+			if stat.Status == storclient.DOWN_SKIP {
+				hooks.OnSkip(stat)
+			} else {
+				hooks.OnComplete(stat)
+			}
+		}
+		assert.Equal(t, 0, e.Wait())
+
+		entries := readManifest(t, manifest.Bytes())
+		assert.Len(t, entries, 4)
+
+		for _, key := range []string{testSha1 + " ok", testSha2 + " skip"} {
+			entry := entries[key]
+			if assert.NotNil(t, entry.ExitCode, key) {
+				assert.Equal(t, 0, *entry.ExitCode)
+			}
+			assert.True(t, entry.Deleted)
+			assert.NoFileExists(t, filepath.Join(tempdir, entry.Path))
+		}
+
+		assert.Nil(t, entries["failed fail"].ExitCode)
+	})
+
+	t.Run("failed command", func(t *testing.T) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, testSha1), []byte(testSha1), 0644))
+
+		var manifest bytes.Buffer
+		e := newExecutor("exit 3", tempdir, 1, true, storclient.NewManifest(&manifest), storclient.DefaultAlgorithm)
+		e.hooks().OnComplete(stats[0])
+		// file is missing (deleted by previous run)
+		e.hooks().OnSkip(stats[1])
+		assert.Equal(t, 1, e.Wait())
+
+		entries := readManifest(t, manifest.Bytes())
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		assert.Equal(t, []string{testSha1 + " ok", testSha2 + " skip"}, keys)
+
+		if assert.NotNil(t, entries[testSha1+" ok"].ExitCode) {
+			assert.Equal(t, 3, *entries[testSha1+" ok"].ExitCode)
+		}
+		assert.False(t, entries[testSha1+" ok"].Deleted)
+		assert.FileExists(t, filepath.Join(tempdir, testSha1))
+		assert.Nil(t, entries[testSha2+" skip"].ExitCode)
+	})
+
+	t.Run("skip before ok", func(t *testing.T) {
+		// file is downloaded by other worker, its DOWN_SKIP is processed before its DOWN_OK
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, testSha1), []byte(testSha1), 0644))
+		runs := filepath.Join(tempdir, "runs")
+
+		var manifest bytes.Buffer
+		e := newExecutor("echo {sha} >> "+shellQuote(runs), tempdir, 2, false, storclient.NewManifest(&manifest), storclient.DefaultAlgorithm)
+		e.hooks().OnSkip(stats[2])
+		e.hooks().OnComplete(stats[0])
+		assert.Equal(t, 0, e.Wait())
+
+		content, err := ioutil.ReadFile(runs)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, bytes.Count(content, []byte("\n")), "command runs once")
+
+		entries := readManifest(t, manifest.Bytes())
+		if assert.NotNil(t, entries[testSha1+" ok"].ExitCode, "exit code is in record of download") {
+			assert.Equal(t, 0, *entries[testSha1+" ok"].ExitCode)
+		}
+		assert.Nil(t, entries[testSha1+" skip"].ExitCode)
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/avast/stor-client/client"
+	log "github.com/sirupsen/logrus"
+)
+
+func runExists() error {
+	client, err := newClient(*storageUrl, "", clientOpts())
+	if err != nil {
+		return err
+	}
+
+	input, objects, err := readInput()
+	if err != nil {
+		return err
+	}
+
+	missing, failed := exists(client, objects, *max, os.Stdout)
+
+	if input.invalid > 0 {
+		return fmt.Errorf("%d invalid lines or unreadable inputs", input.invalid)
+	}
+
+	if missing > 0 || failed > 0 {
+		return fmt.Errorf("%d objects are missing, check of %d objects fail", missing, failed)
+	}
+
+	return nil
+}
+
+// exists writes line "SHA exists" or "SHA missing" to w for every object
+// and returns count of missing objects and count of objects which check fails
+func exists(client *storclient.StorClient, objects <-chan storclient.Object, max int, w io.Writer) (missing int, failed int) {
+	var lock sync.Mutex
+
+	forEachObject(objects, max, func(object storclient.Object) {
+		found, err := client.Exists(context.Background(), object.Sha)
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch {
+		case err != nil:
+			log.Errorf("Check of %s fail: %s", object.Sha, err)
+			failed++
+		case found:
+			fmt.Fprintf(w, "%s exists\n", object.Sha)
+		default:
+			fmt.Fprintf(w, "%s missing\n", object.Sha)
+			missing++
+		}
+	})
+
+	return missing, failed
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExists(t *testing.T) {
+	server, client, _ := newTestStorage(t, map[string][]byte{helloSha: []byte("hello")})
+	defer server.Close()
+
+	var out bytes.Buffer
+	missing, failed := exists(client, objectsOf(t, helloSha, testSha1, testSha2), 2, &out)
+	assert.Equal(t, 2, missing)
+	assert.Equal(t, 0, failed)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(lines)
+	assert.Equal(t, []string{
+		testSha1 + " missing",
+		helloSha + " exists",
+		testSha2 + " missing",
+	}, lines)
+}
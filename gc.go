@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// name of temporary file of download (SHA_random.temp),
+// of zip archive (NAME_random.zip.temp) or of wrapped file (NAME_random.wrap.temp), NAME is base name of any file
+var tempFileName = regexp.MustCompile(`^([a-fA-F0-9]+_.*\.temp[0-9]*|.+_[0-9]+\.(zip|wrap)\.temp)$`)
+
+func isTempFile(name string) bool {
+	return tempFileName.MatchString(name)
+}
+
+func runGC() error {
+	_, err := gc(*gcDir, *gcOlderThan, *gcDryRun, os.Stdout)
+	return err
+}
+
+// gc removes temporary files of interrupted downloads older than olderThan from dir (recursively)
+// and writes their paths to w, dryRun only writes paths
+func gc(dir string, olderThan time.Duration, dryRun bool, w io.Writer) (removed int, err error) {
+	deadline := time.Now().Add(-olderThan)
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() || !isTempFile(info.Name()) || info.ModTime().After(deadline) {
+			return nil
+		}
+
+		fmt.Fprintln(w, path)
+		if dryRun {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+
+		return nil
+	})
+
+	log.Infof("Removed %d temporary files", removed)
+
+	return removed, err
+}
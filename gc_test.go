@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGC(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gc")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	old := filepath.Join(tempdir, testSha1+"_123.temp")
+	running := filepath.Join(tempdir, testSha2+"_456.temp")
+	sample := filepath.Join(tempdir, testSha1)
+	// temporary files of zip archive and wrapped file named by --path-template
+	zip := filepath.Join(tempdir, "report.dat_789.zip.temp")
+	wrap := filepath.Join(tempdir, "Setup Tool.exe_012.wrap.temp")
+	notes := filepath.Join(tempdir, "notes_1.temp")
+	for _, path := range []string{old, running, sample, zip, wrap, notes} {
+		assert.NoError(t, ioutil.WriteFile(path, nil, 0644))
+	}
+	oldTime := time.Now().Add(-2 * time.Hour)
+	for _, path := range []string{old, sample, zip, wrap, notes} {
+		assert.NoError(t, os.Chtimes(path, oldTime, oldTime))
+	}
+	expected := old + "\n" + wrap + "\n" + zip + "\n"
+
+	var out bytes.Buffer
+	removed, err := gc(tempdir, time.Hour, true, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed, "dry run")
+	assert.Equal(t, expected, out.String())
+
+	out.Reset()
+	removed, err = gc(tempdir, time.Hour, false, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, removed)
+	assert.Equal(t, expected, out.String())
+
+	for path, exists := range map[string]bool{old: false, running: true, sample: true, zip: false, wrap: false, notes: true} {
+		_, err := os.Stat(path)
+		assert.Equal(t, exists, err == nil, path)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/avast/stor-client/client"
+)
+
+func runGet() error {
+	client, err := newClient(*storageUrl, "", clientOpts())
+	if err != nil {
+		return err
+	}
+
+	return get(client, storclient.Algorithm(*algorithm), *getSha, *getDestination)
+}
+
+// get writes content of object sha to destination ('-' means stdout), content is verified
+//
+// destination file is removed if download or verification fails
+func get(client *storclient.StorClient, algorithm storclient.Algorithm, shaHexStr, destination string) (err error) {
+	sha, err := algorithm.ParseHash(shaHexStr)
+	if err != nil {
+		return err
+	}
+
+	reader, err := client.Get(context.Background(), sha)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := reader.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+	}()
+
+	if destination == "-" {
+		_, err = io.Copy(os.Stdout, reader)
+		return err
+	}
+
+	out, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, reader); err != nil {
+		_ = out.Close()
+		_ = os.Remove(destination)
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	server, client, _ := newTestStorage(t, map[string][]byte{helloSha: []byte("hello"), testSha1: []byte("corrupted")})
+	defer server.Close()
+
+	tempdir, err := ioutil.TempDir("", "get")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	destination := filepath.Join(tempdir, "hello")
+	assert.NoError(t, get(client, storclient.AlgorithmSHA256, helloSha, destination))
+	content, err := ioutil.ReadFile(destination)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	corrupted := filepath.Join(tempdir, "corrupted")
+	assert.Error(t, get(client, storclient.AlgorithmSHA256, testSha1, corrupted))
+	_, err = os.Stat(corrupted)
+	assert.True(t, os.IsNotExist(err), "corrupted destination is removed")
+
+	assert.Error(t, get(client, storclient.AlgorithmSHA256, testSha2, filepath.Join(tempdir, "missing")))
+	assert.Error(t, get(client, storclient.AlgorithmSHA256, "nosha", filepath.Join(tempdir, "nosha")))
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/avast/stor-client/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// fields of structured input (also default names of columns/keys)
+const (
+	fieldSha      = "sha256"
+	fieldName     = "name"
+	fieldDir      = "dir"
+	fieldPriority = "priority"
+	fieldTags     = "tags"
+	fieldVersion  = "version"
+)
+
+// field with original identifier (md5:<hex>, sha1:<hex>) of resolved object
+const fieldOrigin = "origin"
+
+// separator of tags in CSV/TSV column
+const tagsSeparator = ";"
+
+var inputFormats = []string{"sha", "jsonl", "csv", "tsv"}
+
+// fields of structured input
+var inputFields = []string{fieldSha, fieldName, fieldDir, fieldPriority, fieldTags, fieldVersion}
+
+var hexRun = regexp.MustCompile("[a-fA-F0-9]+")
+
+// inputReader parses objects from input
+type inputReader struct {
+	// format of input (see inputFormats)
+	format string
+	// columns maps fields of object to names of columns (CSV/TSV header) or keys (JSON Lines),
+	// default name of column is name of field
+	columns map[string]string
+	// strict - line without valid SHA256 (sha format) is rejected instead of ignored
+	strict bool
+	// resolver of md5/sha1 to SHA256, nil means that only SHA256 is accepted
+	resolver storclient.HashResolver
+	// hash algorithm of objects (SHA256 is hash of this algorithm), default ("") is sha256
+	algorithm storclient.Algorithm
+	// count of rejected lines/records (and unreadable inputs), it is valid after channel of objects is closed
+	invalid int
+}
+
+// readObjects parses objects from rd, unreadable input is logged and counted as invalid
+func (in *inputReader) readObjects(rd io.Reader) <-chan storclient.Object {
+	objects := make(chan storclient.Object, 32)
+
+	go func() {
+		defer close(objects)
+
+		if err := in.read(rd, objects); err != nil {
+			log.Errorf("Read of input fail: %s", err)
+			in.invalid++
+		}
+	}()
+
+	return objects
+}
+
+// read sends objects parsed from rd in format of input and returns error of reading (e.g. truncated or corrupted input)
+func (in *inputReader) read(rd io.Reader, objects chan<- storclient.Object) error {
+	switch in.format {
+	case "jsonl":
+		return in.readJSONLines(rd, objects)
+	case "csv":
+		return in.readCSV(rd, ',', objects)
+	case "tsv":
+		return in.readCSV(rd, '\t', objects)
+	default:
+		return in.readShas(rd, func(sha string) {
+			in.sendObject(objects, map[string]string{fieldSha: sha}, nil)
+		})
+	}
+}
+
+// readShas calls found for every SHA256 (as hex string) in rd, see parseShas
+//
+// md5 and sha1 (hex) are found too if resolver is set
+func (in *inputReader) readShas(rd io.Reader, found func(sha string)) error {
+	scanner := bufio.NewScanner(rd)
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		shas := parseShas(scanner.Text(), in.algorithm)
+		if in.resolver != nil {
+			shas = append(shas, parseResolvableHashes(scanner.Text())...)
+		}
+		if len(shas) == 0 && in.strict && strings.TrimSpace(scanner.Text()) != "" {
+			log.Errorf("No valid sha256 on line %d: %q", line, scanner.Text())
+			in.invalid++
+		}
+
+		for _, sha := range shas {
+			found(sha)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// parseShas returns all SHA256 (as hex string) on line, hashes of other algorithm are parsed same way
+//
+// SHA256 is hex anywhere in token (e.g. path, file name or OCI digest sha256:<hex>)
+// or whole token encoded by base64 or base32, optionally with digest prefix (name of algorithm)
+func parseShas(line string, algorithm storclient.Algorithm) []string {
+	size := algorithm.Size()
+	shas := make([]string, 0)
+
+	for _, token := range strings.FieldsFunc(line, isTokenSeparator) {
+		found := false
+		for _, run := range hexRun.FindAllString(token, -1) {
+			if len(run) == hex.EncodedLen(size) {
+				shas = append(shas, run)
+				found = true
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		if sha, ok := decodeSha(trimDigestPrefix(token, algorithm), size); ok {
+			shas = append(shas, sha)
+		}
+	}
+
+	return shas
+}
+
+// parseResolvableHashes returns all md5 and sha1 (as hex string) on line
+func parseResolvableHashes(line string) []string {
+	hashes := make([]string, 0)
+	for _, run := range hexRun.FindAllString(line, -1) {
+		if storclient.HashType(run) != "" {
+			hashes = append(hashes, run)
+		}
+	}
+
+	return hashes
+}
+
+func isTokenSeparator(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`,;"'|()[]{}<>`, r)
+}
+
+// trimDigestPrefix trims digest prefix (e.g. OCI sha256:<hex> or SRI sha256-<base64>) of algorithm
+func trimDigestPrefix(token string, algorithm storclient.Algorithm) string {
+	name := algorithm.String()
+	for _, prefix := range []string{name + ":", name + "-", strings.ToUpper(name) + ":", strings.ToUpper(name) + "-"} {
+		if strings.HasPrefix(token, prefix) {
+			return token[len(prefix):]
+		}
+	}
+
+	return token
+}
+
+// decodeSha decodes base64 (standard or url) or base32 encoded hash of size (in bytes) to hex string
+//
+// token must have padding of encoding (so ordinary words aren't decoded),
+// base32 without padding (e.g. sha1) must be upper case
+func decodeSha(token string, size int) (string, bool) {
+	var decoded []byte
+	var err error
+
+	switch len(token) {
+	case base64.StdEncoding.EncodedLen(size):
+		if !hasPadding(token, base64.StdEncoding.EncodedLen(size)-base64.RawStdEncoding.EncodedLen(size)) {
+			return "", false
+		}
+
+		decoded, err = base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			decoded, err = base64.URLEncoding.DecodeString(token)
+		}
+	case base32.StdEncoding.EncodedLen(size):
+		padding := base32.StdEncoding.EncodedLen(size) - base32.StdEncoding.WithPadding(base32.NoPadding).EncodedLen(size)
+		if !hasPadding(token, padding) || (padding == 0 && token != strings.ToUpper(token)) {
+			return "", false
+		}
+
+		decoded, err = base32.StdEncoding.DecodeString(strings.ToUpper(token))
+	default:
+		return "", false
+	}
+
+	if err != nil || len(decoded) != size {
+		return "", false
+	}
+
+	return hex.EncodeToString(decoded), true
+}
+
+// hasPadding returns true if token ends with padding of length
+func hasPadding(token string, length int) bool {
+	return strings.HasSuffix(token, strings.Repeat("=", length))
+}
+
+func (in *inputReader) readJSONLines(rd io.Reader, objects chan<- storclient.Object) error {
+	scanner := bufio.NewScanner(rd)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Errorf("Invalid JSON on line %d: %s", line, err)
+			in.invalid++
+			continue
+		}
+
+		fields := make(map[string]string)
+		var tags []string
+		for _, field := range inputFields {
+			switch value := record[in.column(field)].(type) {
+			case string:
+				fields[field] = value
+			case float64:
+				fields[field] = strconv.FormatFloat(value, 'f', -1, 64)
+			case []interface{}:
+				if field != fieldTags {
+					continue
+				}
+				for _, tag := range value {
+					tags = append(tags, fmt.Sprint(tag))
+				}
+			}
+		}
+
+		// tags can be string with separator (same as in CSV/TSV)
+		if fields[fieldTags] != "" {
+			tags = strings.Split(fields[fieldTags], tagsSeparator)
+		}
+
+		in.sendObject(objects, fields, tags)
+	}
+
+	return scanner.Err()
+}
+
+func (in *inputReader) readCSV(rd io.Reader, separator rune, objects chan<- storclient.Object) error {
+	reader := csv.NewReader(rd)
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+
+	if _, ok := index[in.column(fieldSha)]; !ok {
+		return fmt.Errorf("Column %s is missing in header", in.column(fieldSha))
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fields := make(map[string]string)
+		for _, field := range inputFields {
+			if i, ok := index[in.column(field)]; ok && i < len(record) {
+				fields[field] = record[i]
+			}
+		}
+
+		var tags []string
+		if fields[fieldTags] != "" {
+			tags = strings.Split(fields[fieldTags], tagsSeparator)
+		}
+
+		in.sendObject(objects, fields, tags)
+	}
+}
+
+func (in *inputReader) column(field string) string {
+	if name, ok := in.columns[field]; ok {
+		return name
+	}
+
+	return field
+}
+
+// sendObject converts fields of record to object and sends it, invalid record is logged and rejected
+func (in *inputReader) sendObject(objects chan<- storclient.Object, fields map[string]string, tags []string) {
+	if err := in.resolve(fields); err != nil {
+		log.Error(err)
+		in.invalid++
+		return
+	}
+
+	object, err := newObject(fields, tags, in.algorithm)
+	if err != nil {
+		log.Error(err)
+		in.invalid++
+		return
+	}
+
+	objects <- object
+}
+
+// resolve replaces md5/sha1 in sha field by resolved SHA256 and sets origin field
+func (in *inputReader) resolve(fields map[string]string) error {
+	hash := strings.ToLower(strings.TrimSpace(fields[fieldSha]))
+	hashType := storclient.HashType(hash)
+	if in.resolver == nil || hashType == "" {
+		return nil
+	}
+
+	sha, err := in.resolver.Resolve(context.Background(), hash)
+	if err != nil {
+		return fmt.Errorf("Resolve of %s %s fail: %s", hashType, hash, err)
+	}
+
+	fields[fieldSha] = sha.String()
+	fields[fieldOrigin] = hashType + ":" + hash
+
+	return nil
+}
+
+func newObject(fields map[string]string, tags []string, algorithm storclient.Algorithm) (storclient.Object, error) {
+	hash, err := algorithm.ParseHash(strings.TrimSpace(fields[fieldSha]))
+	if err != nil {
+		return storclient.Object{}, fmt.Errorf("Invalid %s %q: %s", algorithm, fields[fieldSha], err)
+	}
+
+	object := storclient.Object{
+		Sha:     hash,
+		Name:    fields[fieldName],
+		Dir:     fields[fieldDir],
+		Tags:    tags,
+		Origin:  fields[fieldOrigin],
+		Version: fields[fieldVersion],
+	}
+
+	if fields[fieldPriority] != "" {
+		if object.Priority, err = strconv.Atoi(fields[fieldPriority]); err != nil {
+			return storclient.Object{}, fmt.Errorf("Invalid priority %q of %s: %s", fields[fieldPriority], hash, err)
+		}
+	}
+
+	return object, nil
+}
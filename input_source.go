@@ -0,0 +1,144 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/avast/stor-client/client"
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+)
+
+// stdinInput is name of input which means STDIN
+const stdinInput = "-"
+
+// readSources parses objects from all inputs (files, '-' means stdin) and SHA256 from file names in all dirs
+//
+// inputs can be glob patterns, compressed inputs (.gz, .zst) are decompressed,
+// unreadable input is logged and counted as invalid
+func (in *inputReader) readSources(inputs []string, dirs []string) <-chan storclient.Object {
+	objects := make(chan storclient.Object, 32)
+
+	go func() {
+		defer close(objects)
+
+		for _, input := range expandInputs(inputs) {
+			if err := in.readInput(input, objects); err != nil {
+				log.Errorf("Read of input %s fail: %s", input, err)
+				in.invalid++
+			}
+		}
+
+		for _, dir := range dirs {
+			if err := in.readDir(dir, objects); err != nil {
+				log.Errorf("Read of directory %s fail: %s", dir, err)
+				in.invalid++
+			}
+		}
+	}()
+
+	return objects
+}
+
+// expandInputs expands glob patterns of inputs, pattern without match is kept as is (open of it fails)
+func expandInputs(inputs []string) []string {
+	expanded := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		matches, err := filepath.Glob(input)
+		if err != nil || len(matches) == 0 {
+			expanded = append(expanded, input)
+			continue
+		}
+
+		expanded = append(expanded, matches...)
+	}
+
+	return expanded
+}
+
+func (in *inputReader) readInput(input string, objects chan<- storclient.Object) error {
+	rd, err := openInput(input)
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	return in.read(rd, objects)
+}
+
+// readDir sends object for every SHA256 in names of files in dir (recursively)
+func (in *inputReader) readDir(dir string, objects chan<- storclient.Object) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		for _, sha := range parseShas(info.Name(), in.algorithm) {
+			in.sendObject(objects, map[string]string{fieldSha: sha}, nil)
+		}
+
+		return nil
+	})
+}
+
+// inputFile is (decompressed) reader of input, Close closes decompressor and file
+type inputFile struct {
+	io.Reader
+	closers []func() error
+}
+
+func (f *inputFile) Close() error {
+	var firstErr error
+	for i := len(f.closers) - 1; i >= 0; i-- {
+		if err := f.closers[i](); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// openInput opens input ('-' means stdin), .gz and .zst inputs are decompressed
+func openInput(input string) (io.ReadCloser, error) {
+	if input == stdinInput {
+		return &inputFile{Reader: os.Stdin}, nil
+	}
+
+	file, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(input, ".gz"):
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		return &inputFile{Reader: gz, closers: []func() error{file.Close, gz.Close}}, nil
+	case strings.HasSuffix(input, ".zst"):
+		zr, err := zstd.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		closeZstd := func() error {
+			zr.Close()
+			return nil
+		}
+
+		return &inputFile{Reader: zr, closers: []func() error{file.Close, closeZstd}}, nil
+	default:
+		return &inputFile{Reader: file, closers: []func() error{file.Close}}, nil
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSources(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "input")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, "plain.txt"), []byte(testSha1+"\n"), 0644))
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, err = gz.Write([]byte(testSha2 + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, "list.gz"), gzipped.Bytes(), 0644))
+
+	var zstded bytes.Buffer
+	zw, err := zstd.NewWriter(&zstded)
+	assert.NoError(t, err)
+	_, err = zw.Write([]byte(testSha1 + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, "list.zst"), zstded.Bytes(), 0644))
+
+	mirror := filepath.Join(tempdir, "mirror")
+	assert.NoError(t, os.MkdirAll(filepath.Join(mirror, "01"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(mirror, "01", testSha1+".dat"), nil, 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(mirror, testSha2+".zip"), nil, 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(mirror, "README"), nil, 0644))
+
+	in := &inputReader{format: "sha"}
+	got := make([]string, 0)
+	inputs := []string{filepath.Join(tempdir, "*.txt"), filepath.Join(tempdir, "list.*"), filepath.Join(tempdir, "missing")}
+	for object := range in.readSources(inputs, []string{mirror}) {
+		got = append(got, object.Sha.String())
+	}
+
+	sort.Strings(got)
+	assert.Equal(t, []string{testSha1, testSha1, testSha1, testSha2, testSha2}, got)
+	assert.Equal(t, 1, in.invalid, "missing input")
+}
+
+func TestReadSourcesUnreadable(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "input")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, err = gz.Write([]byte(testSha1 + "\n" + testSha2 + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	truncated := filepath.Join(tempdir, "truncated.gz")
+	assert.NoError(t, ioutil.WriteFile(truncated, gzipped.Bytes()[:gzipped.Len()-10], 0644))
+
+	noSha := filepath.Join(tempdir, "nosha.csv")
+	assert.NoError(t, ioutil.WriteFile(noSha, []byte("hash,name\n"+testSha1+",a.exe\n"), 0644))
+
+	in := &inputReader{format: "sha"}
+	for range in.readSources([]string{truncated}, nil) {
+	}
+	assert.Equal(t, 1, in.invalid, "truncated input")
+
+	in = &inputReader{format: "csv"}
+	count := 0
+	for range in.readSources([]string{noSha}, nil) {
+		count++
+	}
+	assert.Equal(t, 0, count)
+	assert.Equal(t, 1, in.invalid, "missing sha256 column")
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testSha1 = "01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b"
+	testSha2 = "edeaaff3f1774ad2888673770c6d64097e391bc362d7d6fb34982ddf0efd18cb"
+)
+
+func readAllObjects(input string, format string, columns map[string]string) []storclient.Object {
+	in := &inputReader{format: format, columns: columns}
+
+	got := make([]storclient.Object, 0)
+	for object := range in.readObjects(strings.NewReader(input)) {
+		got = append(got, object)
+	}
+
+	return got
+}
+
+func TestReadObjectsFromReader(t *testing.T) {
+	t.Run("sha", func(t *testing.T) {
+		got := readAllObjects("nosha\n"+testSha1+"\n", "sha", nil)
+		assert.Len(t, got, 1)
+		assert.Equal(t, testSha1, got[0].Sha.String())
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		input := `{"sha256": "` + testSha1 + `", "name": "a.exe", "dir": "x", "priority": 3, "tags": ["pe", "packed"], "version": "v1"}
+invalid json
+{"sha256": "invalid sha"}
+
+{"hash": "` + testSha2 + `"}
+`
+		got := readAllObjects(input, "jsonl", nil)
+		assert.Len(t, got, 1)
+		assert.Equal(t, testSha1, got[0].Sha.String())
+		assert.Equal(t, "a.exe", got[0].Name)
+		assert.Equal(t, "x", got[0].Dir)
+		assert.Equal(t, 3, got[0].Priority)
+		assert.Equal(t, []string{"pe", "packed"}, got[0].Tags)
+		assert.Equal(t, "v1", got[0].Version)
+
+		got = readAllObjects(input, "jsonl", map[string]string{"sha256": "hash"})
+		assert.Len(t, got, 1)
+		assert.Equal(t, testSha2, got[0].Sha.String())
+
+		got = readAllObjects(`{"sha256": "`+testSha1+`", "tags": "pe;packed", "name": ["not", "tags"]}`+"\n", "jsonl", nil)
+		assert.Len(t, got, 1)
+		assert.Equal(t, []string{"pe", "packed"}, got[0].Tags, "tags as string with separator")
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		input := "hash,filename,tags,priority\n" +
+			testSha1 + ",a.exe,pe;packed,1\n" +
+			testSha2 + ",b.exe,,x\n"
+
+		got := readAllObjects(input, "csv", map[string]string{"sha256": "hash", "name": "filename"})
+		assert.Len(t, got, 1, "invalid priority is skipped")
+		assert.Equal(t, testSha1, got[0].Sha.String())
+		assert.Equal(t, "a.exe", got[0].Name)
+		assert.Equal(t, 1, got[0].Priority)
+		assert.Equal(t, []string{"pe", "packed"}, got[0].Tags)
+
+		assert.Empty(t, readAllObjects(input, "csv", nil), "sha256 column is missing")
+	})
+
+	t.Run("tsv", func(t *testing.T) {
+		input := "sha256\tname\n" + testSha1 + "\ta b.exe\n"
+
+		got := readAllObjects(input, "tsv", nil)
+		assert.Len(t, got, 1)
+		assert.Equal(t, "a b.exe", got[0].Name)
+	})
+}
+
+func TestParseShas(t *testing.T) {
+	for line, expected := range map[string][]string{
+		"nosha":                         {},
+		testSha1 + "," + testSha2:       {testSha1, testSha2},
+		"image@sha256:" + testSha1:      {testSha1},
+		"sha512:" + testSha1 + testSha2: {},
+		"sha256-AbpHGcgLb+kRsJGnwFEktk7uzpZOCcBY74+YBdrKVGs=":      {testSha1},
+		"AbpHGcgLb-kRsJGnwFEktk7uzpZOCcBY74-YBdrKVGs=":             {testSha1},
+		"AG5EOGOIBNX6SENQSGT4AUJEWZHO5TUWJYE4AWHPR6MALWWKKRVQ====": {testSha1},
+		"ag5eogoibnx6senqsgt4aujewzho5tuwjye4awhpr6malwwkkrvq====": {testSha1},
+		"invalid.base64.invalid.base64.invalid.base6=":             {},
+	} {
+		assert.Equal(t, expected, parseShas(line, storclient.AlgorithmSHA256), line)
+	}
+}
+
+func TestStrictInput(t *testing.T) {
+	input := testSha1 + "\nnosha\n\n" + testSha2 + "\n"
+
+	in := &inputReader{format: "sha"}
+	for range in.readObjects(strings.NewReader(input)) {
+	}
+	assert.Equal(t, 0, in.invalid)
+
+	in = &inputReader{format: "sha", strict: true}
+	count := 0
+	for range in.readObjects(strings.NewReader(input)) {
+		count++
+	}
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 1, in.invalid)
+
+	in = &inputReader{format: "sha"}
+	for range in.readObjects(strings.NewReader(strings.Repeat("x", bufio.MaxScanTokenSize+1))) {
+	}
+	assert.Equal(t, 1, in.invalid, "too long line")
+}
+
+func TestResolveInput(t *testing.T) {
+	md5 := "0123456789abcdef0123456789abcdef"
+	sha1 := "0123456789abcdef0123456789abcdef01234567"
+
+	resolver, err := storclient.NewMappingResolver(strings.NewReader("md5,sha1,sha256\n" + md5 + ",," + testSha1 + "\n," + sha1 + "," + testSha2 + "\n"))
+	assert.NoError(t, err)
+
+	input := md5 + "\n" + strings.ToUpper(sha1) + " " + testSha1 + "\nffffffffffffffffffffffffffffffff\n"
+
+	in := &inputReader{format: "sha", resolver: resolver}
+	got := make([]storclient.Object, 0)
+	for object := range in.readObjects(strings.NewReader(input)) {
+		got = append(got, object)
+	}
+
+	assert.Len(t, got, 3)
+	assert.Equal(t, testSha1, got[0].Sha.String())
+	assert.Equal(t, "md5:"+md5, got[0].Origin)
+	assert.Equal(t, testSha1, got[1].Sha.String())
+	assert.Equal(t, "", got[1].Origin)
+	assert.Equal(t, testSha2, got[2].Sha.String())
+	assert.Equal(t, "sha1:"+sha1, got[2].Origin)
+	assert.Equal(t, 1, in.invalid, "unknown md5")
+
+	assert.Len(t, readAllObjects(md5+"\n", "sha", nil), 0, "md5 is ignored without resolver")
+}
+
+func TestParseShasOfAlgorithm(t *testing.T) {
+	sha512 := testSha1 + testSha2
+	sha1 := "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+
+	assert.Equal(t, []string{sha512}, parseShas("sha512:"+sha512+" "+testSha1, storclient.AlgorithmSHA512))
+	assert.Equal(t, []string{sha1}, parseShas(sha1+".dat", storclient.AlgorithmSHA1))
+	assert.Equal(t, []string{sha1}, parseShas("sha1-2jmj7l5rSw0yVb/vlWAYkK/YBwk=", storclient.AlgorithmSHA1))
+	assert.Equal(t, []string{}, parseShas(sha1, storclient.AlgorithmSHA256))
+	assert.Equal(t, []string{sha1}, parseShas("3I42H3S6NNFQ2MSVX7XZKYAYSCX5QBYJ", storclient.AlgorithmSHA1))
+	assert.Equal(t, []string{}, parseShas("incomprehensibilitiesoverwritten", storclient.AlgorithmSHA1), "word isn't base32")
+
+	objects := readAllObjects(sha512+"\n", "sha", nil)
+	assert.Len(t, objects, 0, "sha512 isn't sha256")
+
+	in := &inputReader{format: "sha", algorithm: storclient.AlgorithmSHA512}
+	got := make([]storclient.Object, 0)
+	for object := range in.readObjects(strings.NewReader(sha512 + "\n")) {
+		got = append(got, object)
+	}
+	assert.Len(t, got, 1)
+	assert.Equal(t, sha512, got[0].Sha.String())
+}
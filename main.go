@@ -12,13 +12,129 @@ features
 * download retry
 * concurent download (default `4`)
 * S3 download as primary place, stor as fallback
+* output to directory or to tar archive (optionally gzip/zstd compressed) on stdout or file
+* password protected zip archives (password `infected` by default) for sharing of malware samples
+* safe storage - read-only files without execute bits, optionally wrapped (xor, aes) by key
+* configurable path of downloaded files (e.g. sharding to subdirectories `ab/cd/SHA`)
+* all SHA256 on line as hex, digest (`sha256:<hex>`), base64 or base32; strict mode for invalid lines
+* input from stdin, files (optionally gzip/zstd compressed) or names of files in directory
+* configurable hash algorithm of content address (sha256, sha512, blake3, sha1) for other content-addressed stores
+* commands get, exists, stat, verify, upload, gc, mirror, list, presign
+* config file with named profiles, environment variables (STOR_CLIENT_*)
+* md5/sha1 input resolved to SHA256 by mapping service or mapping file (CSV or SQLite database)
+* structured input (JSON Lines, CSV, TSV) with name, subdirectory, priority and tags of files
+* manifest (JSON Lines) with record about every processed file
+* progress bar with rate and ETA on terminal, periodic progress log otherwise (or with --json)
+* prometheus metrics (downloads by status and backend, bytes, retries, latency, queue depth, in-flight) on --metrics-listen
+* OpenTelemetry tracing in golang client (span per sha with queue wait, attempts, requests, transfer and verification; trace context is propagated in request headers)
+* post-download command (--exec, e.g. scanner) for every file with bounded concurrency, exit codes in manifest, optional delete of processed files
+* authentication - bearer token, basic auth and custom headers for stor, AWS Signature V4 for private S3 buckets (static keys, environment, shared credentials file)
+* native S3 backend (--s3-bucket) - S3 API with SigV4, region, path-style or virtual-host addressing, versioned objects, requester pays and listing of bucket; works with MinIO and other S3-compatible servers
+* pre-signed S3 urls (presign) for temporary access to objects without credentials, optionally only for existing objects
 
 cli
 
 read (parse) SHA256 from STDIN and download it to `destinationDir`
 
+all SHA256 on line are used - hex (anywhere, e.g. in path or OCI digest `sha256:<hex>`)
+or base64/base32 encoded (e.g. SRI `sha256-<base64>`), `--strict` rejects lines without SHA256
+
 	echo EE2BF0BFD365EBF829F8D07B197B7A15F39760CD14C6D3BFDFBAD2B145CB72B8 | stor-client --storage http://stor.domain.tld .
 
+or stream downloaded files as tar archive to stdout
+
+	echo EE2BF0BFD365EBF829F8D07B197B7A15F39760CD14C6D3BFDFBAD2B145CB72B8 | stor-client --storage http://stor.domain.tld --output=- --compress=gzip | ssh host tar xzf -
+
+or pack every downloaded file to own password protected zip archive (SHA.zip, password infected)
+
+	echo EE2BF0BFD365EBF829F8D07B197B7A15F39760CD14C6D3BFDFBAD2B145CB72B8 | stor-client --storage http://stor.domain.tld --zip .
+
+or shard downloaded files to subdirectories (ee/2b/ee2bf0...), parent directories are created automatically
+
+	echo EE2BF0BFD365EBF829F8D07B197B7A15F39760CD14C6D3BFDFBAD2B145CB72B8 | stor-client --storage http://stor.domain.tld --path-template='{{.FirstShaByte}}/{{.SecondShaByte}}/{{.Sha}}' .
+
+or read SHA256 from files (compressed .gz/.zst too) or from names of files in other directory
+
+	stor-client --storage http://stor.domain.tld --input=list.txt.gz --input='lists/*.txt' --from-dir=/mnt/other-host/samples .
+
+or resolve md5/sha1 (e.g. from incident report) to SHA256 by mapping service (or --resolve-file mapping.csv / samples.sqlite),
+original identifier is written to manifest
+
+	echo 44d88612fea8a8f36de82e1278abb02f | stor-client --storage http://stor.domain.tld --resolve-url='http://mapping.domain.tld/{{.Type}}/{{.Hash}}' --manifest=manifest.jsonl .
+
+or download from other content-addressed store (e.g. addressed by sha512 on path /sha512/<hex>)
+
+	echo $SHA512 | stor-client --storage http://cas.domain.tld --algorithm=sha512 --stor-template='{{.Algorithm}}/{{.Sha}}' .
+
+or read names (and subdirectories, priorities, tags) of files from structured input (JSON Lines, CSV, TSV)
+and write manifest about every file
+
+	echo '{"sha256": "ee2bf0bfd365ebf829f8d07b197b7a15f39760cd14c6d3bfdfbad2b145cb72b8", "name": "sample.exe", "dir": "incident-42"}' | stor-client --storage http://stor.domain.tld --input-format=jsonl --path-template='{{.Name}}' --manifest=manifest.jsonl .
+
+or download from private S3 bucket (AWS Signature V4, keys from flags, AWS_* environment or shared credentials file)
+with fallback to stor protected by bearer token (--stor-user, --stor-password and --stor-header are supported too)
+
+	STOR_CLIENT_STOR_TOKEN=secret stor-client --storage http://stor.domain.tld --s3host=https://bucket.s3.eu-central-1.amazonaws.com --s3-auth=sigv4 --s3-region=eu-central-1 --s3-profile=samples . < shas.txt
+
+or download from bucket by native S3 API (e.g. local MinIO), stor is fallback;
+version field of structured input selects version of object in versioned bucket
+
+	stor-client --storage http://stor.domain.tld --s3-bucket=samples --s3-endpoint=http://127.0.0.1:9000 --s3-path-style --s3-auth=sigv4 . < shas.txt
+
+or share objects with somebody without credentials by pre-signed urls (valid for --expires, max 7 days),
+--verify checks existence of every object first
+
+	stor-client --s3-bucket=samples --s3-region=eu-central-1 --s3-auth=sigv4 presign --expires=48h --verify < shas.txt
+
+or run command for every downloaded (or skipped) file (exit code is written to manifest, --exec-delete deletes file after exit code 0)
+
+	stor-client --storage http://stor.domain.tld --exec='clamscan --no-summary {path}' --exec-max=8 --exec-delete --manifest=manifest.jsonl . < shas.txt
+
+or store files read-only and wrapped by key (so sample can't be executed by accident)
+
+	echo EE2BF0BFD365EBF829F8D07B197B7A15F39760CD14C6D3BFDFBAD2B145CB72B8 | stor-client --storage http://stor.domain.tld --wrap=aes --wrap-key=secret .
+
+and restore original content (SHA256 is verified)
+
+	stor-client unwrap --wrap-key=secret ee2bf0bfd365ebf829f8d07b197b7a15f39760cd14c6d3bfdfbad2b145cb72b8 sample.exe
+
+commands
+
+other commands share global flags (storage, S3, retry, algorithm, input, logging)
+
+	stor-client get SHA [DESTINATION]      # one object to stdout or file (verified)
+	stor-client exists < shas.txt          # SHA exists|missing, exit code 1 if any is missing
+	stor-client stat SHA...                # metadata (size, last modified, url) as JSON Lines
+	stor-client verify DIR                 # hash of downloaded files against SHA in their names
+	stor-client upload FILE...             # upload files (PUT) to storage
+	stor-client gc DIR                     # remove temporary files of interrupted downloads
+	stor-client mirror --to=URL < shas.txt # copy objects missing on target storage
+	stor-client list PREFIX                # objects of S3 bucket (--s3-bucket) as JSON Lines
+	stor-client presign < shas.txt         # SHA and pre-signed S3 url (--expires, --verify)
+
+configuration
+
+every flag can be set by environment variable (STOR_CLIENT_ prefix, e.g. STOR_CLIENT_STORAGE for --storage)
+or by named profile in config file (~/.config/stor-client.yaml or --config) selected by --profile
+
+	profile: prod
+	profiles:
+	  prod:
+	    storage: http://stor.domain.tld
+	    s3host: https://bucket.s3.eu-central-1.amazonaws.com
+	    max: 8
+	  lab:
+	    storage: http://stor.lab.domain.tld
+
+value is taken from flag, then from environment variable, then from profile
+
+exit codes
+
+download exits with 2 if some files aren't on storage, 3 if some downloads fail transiently (retry can help),
+4 if content of some files doesn't match its SHA, 5 if some input lines are invalid,
+6 if some --exec commands fail (first one of 4, 3, 2, 5, 6 is used),
+1 means fatal error; --summary-json FILE writes summary of download as JSON
+
 golang client
 
 look to github.com/avast/stor-client/client
@@ -27,16 +143,17 @@ look to github.com/avast/stor-client/client
 package main
 
 import (
-	"bufio"
-	"crypto/sha256"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
-	"regexp"
+	"runtime"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/alecthomas/kingpin"
-	"github.com/avast/hashutil-go"
 	"github.com/avast/stor-client/client"
 	log "github.com/sirupsen/logrus"
 )
@@ -44,8 +161,10 @@ import (
 var version = "master"
 
 var (
-	storageUrl    = kingpin.Flag("storage", "storage url").Short('u').Default("http://stor.whale.int.avast.com").URL()
-	downloadDir   = kingpin.Arg("downloadDir", "directory for downloaded files").Required().String()
+	// config and profile are read by applyConfig before parsing
+	_             = kingpin.Flag("config", "config FILE with profiles (default is ~/.config/stor-client.yaml)").PlaceHolder("FILE").String()
+	_             = kingpin.Flag("profile", "profile of config (default is profile set in config)").String()
+	storageUrl    = kingpin.Flag("storage", "storage url").Short('u').URL()
 	max           = kingpin.Flag("max", "max download process").Default(strconv.Itoa(storclient.DefaultMax)).Int()
 	devnull       = kingpin.Flag("devnull", "download file to /dev/null").Bool()
 	verbose       = kingpin.Flag("verbose", "more talkativ output").Short('v').Bool()
@@ -57,11 +176,112 @@ var (
 	upperCase     = kingpin.Flag("upper", "name of file will be upper case (not applied to suffix)").Bool()
 	s3url         = kingpin.Flag("s3host", "host to s3 endpoint with bucket e.g. https://bucket.s3.eu-central-1.amazonaws.com, if is s3url set, first will be use S3, then fallback to stor").URL()
 	s3template    = kingpin.Flag("s3template", "template to S3 path").Default(storclient.DefaultS3Template).String()
+	s3Bucket      = kingpin.Flag("s3-bucket", "bucket of native S3 backend (S3 API with --s3-auth, versions, requester pays; used instead of --s3host, stor is fallback)").String()
+	s3Endpoint    = kingpin.Flag("s3-endpoint", "endpoint of S3 API without bucket, e.g. http://127.0.0.1:9000 for MinIO (default is https://s3.<region>.amazonaws.com)").URL()
+	s3PathStyle   = kingpin.Flag("s3-path-style", "path-style addressing of --s3-bucket (<endpoint>/<bucket>/<key>) instead of virtual-host style, usually needed by S3-compatible servers").Bool()
+	s3Payer       = kingpin.Flag("s3-requester-pays", "requester pays for requests to --s3-bucket (bucket with Requester Pays)").Bool()
+	storToken     = kingpin.Flag("stor-token", "bearer token of requests to stor (prefer STOR_CLIENT_STOR_TOKEN or config to command line)").PlaceHolder("TOKEN").String()
+	storUser      = kingpin.Flag("stor-user", "user of basic authentication of requests to stor").String()
+	storPassword  = kingpin.Flag("stor-password", "password of basic authentication of requests to stor").String()
+	storHeaders   = kingpin.Flag("stor-header", "custom header of requests to stor, e.g. --stor-header X-Api-Key=KEY; can be repeated").PlaceHolder("NAME=VALUE").StringMap()
+	s3AuthMode    = kingpin.Flag("s3-auth", "authentication of requests to S3 (none - anonymous, sigv4 - AWS Signature V4 by --s3-access-key, AWS_* environment or shared credentials file)").Default(s3AuthNone).Enum(s3AuthNone, s3AuthSigV4)
+	s3Region      = kingpin.Flag("s3-region", "region of S3 (signing of requests)").Default(storclient.DefaultRegion).String()
+	s3AccessKey   = kingpin.Flag("s3-access-key", "access key of S3 (default is AWS_ACCESS_KEY_ID or --s3-profile of shared credentials file)").PlaceHolder("KEY").String()
+	s3SecretKey   = kingpin.Flag("s3-secret-key", "secret key of S3").PlaceHolder("KEY").String()
+	s3Token       = kingpin.Flag("s3-session-token", "session token of temporary credentials of S3").PlaceHolder("TOKEN").String()
+	s3CredsFile   = kingpin.Flag("s3-credentials-file", "shared credentials file (default is AWS_SHARED_CREDENTIALS_FILE or ~/.aws/credentials)").PlaceHolder("FILE").String()
+	s3Profile     = kingpin.Flag("s3-profile", "profile of shared credentials file (default is AWS_PROFILE or default)").String()
+	s3Headers     = kingpin.Flag("s3-header", "custom header of requests to S3 (signed by sigv4); can be repeated").PlaceHolder("NAME=VALUE").StringMap()
+	storTemplate  = kingpin.Flag("stor-template", "template to path of object on storage, e.g. '{{.Algorithm}}/{{.Sha}}'").Default(storclient.DefaultStorTemplate).String()
+	algorithm     = kingpin.Flag("algorithm", "hash algorithm of content address of objects (sha256, sha512, blake3, sha1) - used for parsing of input, urls and verification").Default(string(storclient.DefaultAlgorithm)).Enum(algorithmNames()...)
+	pathTemplate  = kingpin.Flag("path-template", "template to path of downloaded file (suffix is appended, parent directories are created), e.g. '{{.FirstShaByte}}/{{.SecondShaByte}}/{{.Sha}}'; fields: Sha, UpperSha, FirstShaByte, SecondShaByte, ThirdShaByte, Name").String()
+	output        = kingpin.Flag("output", "write downloaded files to archive FILE ('-' means stdout) instead of downloadDir").Short('o').PlaceHolder("FILE").String()
+	format        = kingpin.Flag("format", "format of --output archive (tar, zip)").Default("tar").Enum("tar", "zip")
+	compress      = kingpin.Flag("compress", "compression of tar archive (none, gzip, zstd)").Default("none").Enum("none", "gzip", "zstd")
+	zipPerFile    = kingpin.Flag("zip", "pack every downloaded file to own zip archive (SHA.zip) in downloadDir").Bool()
+	zipPassword   = kingpin.Flag("zip-password", "password of zip archives, empty means without encryption").Default(storclient.DefaultZipPassword).String()
+	safe          = kingpin.Flag("safe", "store downloaded files read-only and without execute bits").Bool()
+	wrap          = kingpin.Flag("wrap", "wrap content of stored files by --wrap-key (none, xor, aes), implies --safe").Default("none").Enum("none", "xor", "aes")
+	wrapKey       = kingpin.Flag("wrap-key", "key of wrapping (see --wrap and unwrap command)").PlaceHolder("KEY").String()
+	inputFormat   = kingpin.Flag("input-format", "format of input (sha - SHA256 anywhere on line, jsonl - JSON Lines, csv, tsv - with header)").Default("sha").Enum(inputFormats...)
+	columns       = kingpin.Flag("column", "mapping of field (sha256, name, dir, priority, tags, version) to column of csv/tsv header or key of JSON Lines, e.g. --column sha256=hash").PlaceHolder("FIELD=COLUMN").StringMap()
+	inputs        = kingpin.Flag("input", "read input from FILE ('-' means stdin, glob patterns are expanded, .gz/.zst are decompressed), can be repeated; default is stdin").Short('i').PlaceHolder("FILE").Strings()
+	fromDirs      = kingpin.Flag("from-dir", "download SHA256 parsed from names of files in DIR (recursively), e.g. mirror of other host; can be repeated").PlaceHolder("DIR").ExistingDirs()
+	resolveURL    = kingpin.Flag("resolve-url", "resolve md5/sha1 of input to SHA256 by mapping service, template of url with fields Hash and Type (md5, sha1), e.g. 'http://mapping.domain.tld/{{.Type}}/{{.Hash}}'").PlaceHolder("TEMPLATE").String()
+	resolveFile   = kingpin.Flag("resolve-file", "resolve md5/sha1 of input to SHA256 by mapping FILE - CSV (with header md5, sha1, sha256) or SQLite database (see --resolve-query)").PlaceHolder("FILE").ExistingFile()
+	resolveQuery  = kingpin.Flag("resolve-query", "query of mapping in SQLite --resolve-file, columns sha256 and md5 and/or sha1").Default(defaultResolveQuery).String()
+	strict        = kingpin.Flag("strict", "reject (log and count) input lines without valid SHA256, exit code is non-zero if any line is rejected").Bool()
+	metricsListen = kingpin.Flag("metrics-listen", "serve prometheus metrics on http://ADDR/metrics, e.g. :9090").PlaceHolder("ADDR").String()
+	execCommand   = kingpin.Flag("exec", "run shell COMMAND for every downloaded (or skipped) file, placeholders {path}, {sha} and {name} are replaced by quoted values, exit code is written to manifest, e.g. 'clamscan {path}'").PlaceHolder("COMMAND").String()
+	execMax       = kingpin.Flag("exec-max", "max count of concurrently running --exec commands").Default(strconv.Itoa(runtime.NumCPU())).Int()
+	execDelete    = kingpin.Flag("exec-delete", "delete file after successful (exit code 0) --exec command").Bool()
+	summaryJSON   = kingpin.Flag("summary-json", "write summary of download (counts by reason, per backend statistics, percentiles, exit code) as JSON to FILE ('-' means stdout)").PlaceHolder("FILE").String()
+	progress      = kingpin.Flag("progress", "progress reporting (auto - bar on terminal, log otherwise or with --json; bar, log, none)").Default(progressAuto).Enum(progressModes...)
+	progressEvery = kingpin.Flag("progress-interval", "interval of progress log lines").Default("10s").Duration()
+	manifest      = kingpin.Flag("manifest", "write record (JSON Lines) about every processed file to FILE").PlaceHolder("FILE").String()
+
+	downloadCommand = kingpin.Command("download", "read (parse) SHA256 from STDIN (or --input, --from-dir) and download it to downloadDir").Default()
+	downloadDir     = downloadCommand.Arg("downloadDir", "directory for downloaded files (required if --output isn't set)").String()
+
+	getCommand     = kingpin.Command("get", "download one object to destination (stdout by default), content is verified")
+	getSha         = getCommand.Arg("sha", "SHA256 of object").Required().String()
+	getDestination = getCommand.Arg("destination", "destination of content ('-' means stdout)").Default("-").String()
+
+	existsCommand = kingpin.Command("exists", "check existence of objects from input (same as download) on storage, print SHA256 with 'exists' or 'missing'")
+
+	statCommand = kingpin.Command("stat", "print metadata (JSON Lines) of objects on storage")
+	statShas    = statCommand.Arg("sha", "SHA256 of objects").Required().Strings()
+
+	verifyCommand = kingpin.Command("verify", "verify content of files in directory (recursively) against SHA256 in their names, print mismatched files")
+	verifyDir     = verifyCommand.Arg("dir", "directory with downloaded files").Required().ExistingDir()
+
+	uploadCommand = kingpin.Command("upload", "upload files to storage (PUT), print SHA256 and file")
+	uploadFiles   = uploadCommand.Arg("file", "files to upload").Required().ExistingFiles()
+
+	gcCommand   = kingpin.Command("gc", "remove temporary files of interrupted downloads from directory (recursively)")
+	gcDir       = gcCommand.Arg("dir", "directory with downloaded files").Required().ExistingDir()
+	gcOlderThan = gcCommand.Flag("older-than", "remove only temporary files older than duration (running downloads are kept)").Default("1h").Duration()
+	gcDryRun    = gcCommand.Flag("dry-run", "only print temporary files").Bool()
+
+	listCommand = kingpin.Command("list", "list objects of S3 bucket (--s3-bucket) by ListObjectsV2, print JSON Lines (key, SHA parsed from key, size, last modified, etag)")
+	listPrefix  = listCommand.Arg("prefix", "prefix of keys, e.g. 'ee/2b/'").String()
+
+	presignCommand = kingpin.Command("presign", "print SHA256 with pre-signed S3 url (--s3-bucket, credentials are required) of objects from input (same as download), e.g. temporary access for somebody without credentials")
+	presignExpires = presignCommand.Flag("expires", "validity of pre-signed urls (max 168h)").Default("24h").Duration()
+	presignVerify  = presignCommand.Flag("verify", "check existence of objects (HEAD) before pre-sign").Bool()
+
+	mirrorCommand = kingpin.Command("mirror", "copy objects from input (same as download) missing on target storage from storage (--storage, --s3host) to target storage")
+	mirrorTo      = mirrorCommand.Flag("to", "url of target storage (required)").URL()
+	mirrorToken   = mirrorCommand.Flag("to-token", "bearer token of requests to target storage (--stor-* authentication is used for source only)").PlaceHolder("TOKEN").String()
+	mirrorUser    = mirrorCommand.Flag("to-user", "user of basic authentication of requests to target storage").String()
+	mirrorPass    = mirrorCommand.Flag("to-password", "password of basic authentication of requests to target storage").String()
+	mirrorHeaders = mirrorCommand.Flag("to-header", "custom header of requests to target storage; can be repeated").PlaceHolder("NAME=VALUE").StringMap()
+
+	unwrapCommand     = kingpin.Command("unwrap", "restore original content of file wrapped by --wrap and verify its SHA256")
+	unwrapSha         = unwrapCommand.Flag("sha", "expected SHA256 of original content (default is parsed from file name)").String()
+	unwrapFile        = unwrapCommand.Arg("file", "wrapped file").Required().ExistingFile()
+	unwrapDestination = unwrapCommand.Arg("destination", "destination of original content ('-' means stdout)").Default("-").String()
 )
 
+var wrappings = map[string]storclient.Wrapping{
+	"none": storclient.WrapNone,
+	"xor":  storclient.WrapXOR,
+	"aes":  storclient.WrapAES,
+}
+
+var compressions = map[string]storclient.Compression{
+	"none": storclient.CompressionNone,
+	"gzip": storclient.CompressionGzip,
+	"zstd": storclient.CompressionZstd,
+}
+
 func main() {
 	kingpin.Version(version)
-	kingpin.Parse()
+	setEnvars(kingpin.CommandLine)
+	if err := applyConfig(kingpin.CommandLine, os.Args[1:]); err != nil {
+		kingpin.Fatalf("%s", err)
+	}
+	command := kingpin.Parse()
 
 	if *verbose {
 		log.SetLevel(log.DebugLevel)
@@ -71,8 +291,150 @@ func main() {
 		log.SetFormatter(&log.JSONFormatter{})
 	}
 
+	if *metricsListen != "" {
+		if err := startMetrics(*metricsListen); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var err error
+	switch command {
+	case unwrapCommand.FullCommand():
+		err = unwrap(*unwrapFile, *unwrapDestination, *unwrapSha, []byte(*wrapKey), storclient.Algorithm(*algorithm))
+	case getCommand.FullCommand():
+		err = runGet()
+	case existsCommand.FullCommand():
+		err = runExists()
+	case statCommand.FullCommand():
+		err = runStat()
+	case verifyCommand.FullCommand():
+		err = runVerify()
+	case uploadCommand.FullCommand():
+		err = runUpload()
+	case gcCommand.FullCommand():
+		err = runGC()
+	case mirrorCommand.FullCommand():
+		err = runMirror()
+	case listCommand.FullCommand():
+		err = runList()
+	case presignCommand.FullCommand():
+		err = runPresign()
+	default:
+		err = runDownload()
+	}
+
+	if codeErr, ok := err.(exitCodeError); ok {
+		log.Error(codeErr)
+		os.Exit(codeErr.code)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runDownload() error {
+	if *downloadDir == "" && *output == "" {
+		kingpin.Fatalf("required argument 'downloadDir' or flag '--output' not provided")
+	}
+
+	if *execCommand != "" && runtime.GOOS == "windows" {
+		return fmt.Errorf("--exec isn't supported on windows (command is run by sh)")
+	}
+
+	if *execCommand != "" && (*output != "" || *zipPerFile || wrappings[*wrap] != storclient.WrapNone || *devnull) {
+		return fmt.Errorf("--exec requires plain files in downloadDir (it can't be combined with --output, --zip, --wrap or --devnull)")
+	}
+
+	out, outFile, err := openOutput(*output, *format, compressions[*compress], *zipPassword)
+	if err != nil {
+		return err
+	}
+
+	if out == nil && *zipPerFile {
+		out = storclient.NewZipDirOutput(*downloadDir, *zipPassword)
+	}
+
+	if out == nil && (*safe || wrappings[*wrap] != storclient.WrapNone) {
+		out, err = storclient.NewSafeDirOutput(*downloadDir, storclient.SafeOpts{Wrapping: wrappings[*wrap], Key: []byte(*wrapKey)})
+		if err != nil {
+			return err
+		}
+	}
+
+	opts := clientOpts()
+	opts.Output = out
+
+	var manifestFile *os.File
+	if *manifest != "" {
+		if manifestFile, err = os.Create(*manifest); err != nil {
+			return err
+		}
+		opts.Manifest = storclient.NewManifest(manifestFile)
+	}
+
+	var executor *executor
+	if *execCommand != "" {
+		// manifest is written by executor (with exit codes of commands)
+		executor = newExecutor(*execCommand, *downloadDir, *execMax, *execDelete, opts.Manifest, opts.Algorithm)
+		opts.Hooks = executor.hooks()
+		opts.Manifest = nil
+	}
+
 	startTime := time.Now()
-	client, err := storclient.New(**storageUrl, *downloadDir, storclient.StorClientOpts{
+	client, err := newClient(*storageUrl, *downloadDir, opts)
+	if err != nil {
+		return err
+	}
+	client.Start()
+
+	input, objects, err := readInput()
+	if err != nil {
+		return err
+	}
+
+	reporter := startProgress(client, progressMode(*progress, *logJson), *progressEvery, startTime)
+	for object := range objects {
+		client.DownloadObject(object)
+	}
+
+	total := client.Wait()
+	reporter.Stop()
+	if err := client.Close(); err != nil {
+		return err
+	}
+
+	execFailed := 0
+	if executor != nil {
+		execFailed = executor.Wait()
+	}
+
+	if outFile != nil {
+		if err := outFile.Close(); err != nil {
+			return err
+		}
+	}
+
+	if manifestFile != nil {
+		if err := manifestFile.Close(); err != nil {
+			return err
+		}
+	}
+
+	total.Print(startTime)
+
+	if *summaryJSON != "" {
+		if err := writeSummary(*summaryJSON, total, time.Since(startTime), input.invalid, execFailed); err != nil {
+			return err
+		}
+	}
+
+	return downloadResult(total, input.invalid, execFailed)
+}
+
+// clientOpts returns options of client from global flags
+func clientOpts() storclient.StorClientOpts {
+	return storclient.StorClientOpts{
 		Max:           *max,
 		Devnull:       *devnull,
 		Timeout:       *timeout,
@@ -82,45 +444,135 @@ func main() {
 		UpperCase:     *upperCase,
 		S3URL:         *s3url,
 		S3Template:    *s3template,
-	})
+		StorTemplate:  *storTemplate,
+		Algorithm:     storclient.Algorithm(*algorithm),
+		PathTemplate:  *pathTemplate,
+		Metrics:       clientMetrics,
+		StorAuth:      storAuth(*storToken, *storUser, *storPassword, *storHeaders),
+		S3:            flagS3Config(),
+		S3Auth:        flagS3Auth(),
+	}
+}
+
+// newClient returns client of storage, storage is required
+func newClient(storage *url.URL, downloadDir string, opts storclient.StorClientOpts) (*storclient.StorClient, error) {
+	if storage == nil {
+		return nil, fmt.Errorf("required flag '--storage' not provided (set it by flag, %s or profile of config)", flagEnvar("storage"))
+	}
+
+	return storclient.New(*storage, downloadDir, opts)
+}
+
+// readInput returns objects from inputs (--input, --from-dir, stdin by default) in --input-format,
+// count of invalid lines of returned inputReader is valid after channel of objects is closed
+func readInput() (*inputReader, <-chan storclient.Object, error) {
+	if len(*inputs) == 0 && len(*fromDirs) == 0 {
+		*inputs = []string{stdinInput}
+	}
+
+	resolver, err := newResolver(*resolveURL, *resolveFile, *resolveQuery, *timeout)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
-	client.Start()
 
-	shas := readShaFromReader(os.Stdin)
-	for shaHexStr := range shas {
+	if resolver != nil && storclient.Algorithm(*algorithm) != storclient.AlgorithmSHA256 {
+		return nil, nil, fmt.Errorf("md5/sha1 can be resolved only to sha256, not to %s", *algorithm)
+	}
 
-		if hash, err := hashutil.StringToHash(sha256.New(), shaHexStr); err == nil {
-			client.Download(hash)
-		} else {
-			log.Error("Invalid sha256: ", err)
-		}
+	input := &inputReader{format: *inputFormat, columns: *columns, strict: *strict, resolver: resolver, algorithm: storclient.Algorithm(*algorithm)}
+
+	return input, input.readSources(*inputs, *fromDirs), nil
+}
+
+// forEachObject calls fn for every object by max concurrent goroutines and waits for all calls
+func forEachObject(objects <-chan storclient.Object, max int, fn func(storclient.Object)) {
+	if max < 1 {
+		max = 1
 	}
 
-	total := client.Wait()
+	var wg sync.WaitGroup
+	for i := 0; i < max; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range objects {
+				fn(object)
+			}
+		}()
+	}
 
-	total.Print(startTime)
+	wg.Wait()
+}
 
-	if !total.Status() {
-		os.Exit(1)
+func algorithmNames() []string {
+	names := make([]string, 0, len(storclient.Algorithms))
+	for _, algorithm := range storclient.Algorithms {
+		names = append(names, string(algorithm))
 	}
+
+	return names
 }
 
-func readShaFromReader(rd io.Reader) <-chan string {
-	shas := make(chan string, 32)
+// newResolver returns resolver of md5/sha1 by mapping service (urlTemplate) or mapping file (CSV or SQLite database with query)
+// nil resolver means that resolving isn't requested
+func newResolver(urlTemplate string, mappingFile string, query string, timeout time.Duration) (storclient.HashResolver, error) {
+	if urlTemplate != "" {
+		return storclient.NewHTTPResolver(urlTemplate, &http.Client{Timeout: timeout})
+	}
 
-	go func() {
-		re := regexp.MustCompile("[a-fA-F0-9]{64}")
-		scanner := bufio.NewScanner(rd)
-		for scanner.Scan() {
-			for _, sha := range re.FindStringSubmatch(scanner.Text()) {
-				shas <- sha
-			}
-		}
+	if mappingFile == "" {
+		return nil, nil
+	}
 
-		close(shas)
-	}()
+	sqlite, err := isSQLite(mappingFile)
+	if err != nil {
+		return nil, err
+	}
+	if sqlite {
+		return newSQLiteResolver(mappingFile, query)
+	}
+
+	file, err := os.Open(mappingFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return storclient.NewMappingResolver(file)
+}
+
+// openOutput returns archive output to file path ('-' means stdout) and opened file (must be closed)
+// empty path means default output (files in downloadDir)
+func openOutput(path string, format string, compression storclient.Compression, zipPassword string) (storclient.Output, *os.File, error) {
+	if path == "" {
+		return nil, nil, nil
+	}
+
+	if path == "-" {
+		out, err := newArchiveOutput(os.Stdout, format, compression, zipPassword)
+		return out, nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out, err := newArchiveOutput(file, format, compression, zipPassword)
+	if err != nil {
+		// don't leave empty archive behind
+		_ = file.Close()
+		_ = os.Remove(path)
+		return nil, nil, err
+	}
+
+	return out, file, nil
+}
+
+func newArchiveOutput(w io.Writer, format string, compression storclient.Compression, zipPassword string) (storclient.Output, error) {
+	if format == "zip" {
+		return storclient.NewZipOutput(w, zipPassword), nil
+	}
 
-	return shas
+	return storclient.NewTarOutput(w, compression)
 }
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/avast/stor-client/client"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,12 +29,102 @@ a/b/c/edeaaff3f1774ad2888673770c6d64097e391bc362d7d6fb34982ddf0efd18cb
 `
 	r := strings.NewReader(x)
 
-	shas := readShaFromReader(r)
-
 	got := make([]string, 0)
-	for sha := range shas {
+	err := (&inputReader{}).readShas(r, func(sha string) {
 		got = append(got, sha)
-	}
+	})
+	assert.NoError(t, err)
 
 	assert.Equal(t, expected, got)
 }
+
+// testStorage is in-memory storage serving GET, HEAD and PUT of /SHA
+type testStorage struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+}
+
+func newTestStorage(t *testing.T, objects map[string][]byte) (*httptest.Server, *storclient.StorClient, *testStorage) {
+	storage := &testStorage{objects: objects}
+	server := httptest.NewServer(storage)
+
+	serverURL, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	client, err := newClient(serverURL, "", storclient.StorClientOpts{RetryAttempts: 1})
+	assert.NoError(t, err)
+
+	return server, client, storage
+}
+
+func (s *testStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sha := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodPut:
+		content, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[sha] = content
+		w.WriteHeader(http.StatusCreated)
+	default:
+		content, ok := s.objects[sha]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}
+}
+
+func (s *testStorage) get(sha string) ([]byte, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	content, ok := s.objects[sha]
+	return content, ok
+}
+
+func TestNewClientWithoutStorage(t *testing.T) {
+	_, err := newClient(nil, "", storclient.StorClientOpts{})
+	assert.Error(t, err)
+}
+
+// sha256 of "hello"
+const helloSha = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func objectsOf(t *testing.T, shas ...string) <-chan storclient.Object {
+	objects := make(chan storclient.Object, len(shas))
+	for _, sha := range shas {
+		hash, err := storclient.AlgorithmSHA256.ParseHash(sha)
+		assert.NoError(t, err)
+		objects <- storclient.Object{Sha: hash}
+	}
+	close(objects)
+
+	return objects
+}
+
+func TestOpenOutput(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "output")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	path := filepath.Join(tempdir, "samples.tar")
+	_, file, err := openOutput(path, "tar", storclient.Compression("unknown"), "")
+	assert.Error(t, err)
+	assert.Nil(t, file)
+	assert.NoFileExists(t, path, "archive is removed if output fails")
+
+	out, file, err := openOutput(path, "tar", storclient.CompressionNone, "")
+	assert.NoError(t, err)
+	assert.NoError(t, out.Close())
+	assert.NoError(t, file.Close())
+	assert.FileExists(t, path)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/avast/stor-client/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// path of metrics endpoint
+const metricsPath = "/metrics"
+
+// metrics of all clients, nil if --metrics-listen isn't set
+var clientMetrics *storclient.Metrics
+
+// startMetrics registers metrics of clients to default prometheus registry
+// and serves them (with metrics of process) on addr
+func startMetrics(addr string) error {
+	metrics, err := storclient.NewMetrics(prometheus.DefaultRegisterer)
+	if err != nil {
+		return err
+	}
+
+	listener, err := serveMetrics(addr, prometheus.DefaultGatherer)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Metrics are served on http://%s%s", listener.Addr(), metricsPath)
+	clientMetrics = metrics
+
+	return nil
+}
+
+// serveMetrics serves metrics of gatherer on metricsPath of addr in background,
+// listener is returned after successful bind
+func serveMetrics(addr string, gatherer prometheus.Gatherer) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Debugf("Metrics server end: %s", err)
+		}
+	}()
+
+	return listener, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	_, err := storclient.NewMetrics(registry)
+	assert.NoError(t, err)
+
+	listener, err := serveMetrics("127.0.0.1:0", registry)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, listener.Close())
+	}()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + metricsPath)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "stor_client_queue_depth 0")
+
+	_, err = serveMetrics(listener.Addr().String(), registry)
+	assert.Error(t, err, "address in use")
+}
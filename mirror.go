@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/avast/stor-client/client"
+	log "github.com/sirupsen/logrus"
+)
+
+func runMirror() error {
+	// not kingpin's Required, it would refuse value from profile
+	if *mirrorTo == nil {
+		return fmt.Errorf("required flag '--to' not provided (set it by flag, %s or profile of config)", commandFlagEnvar("mirror", "to"))
+	}
+
+	source, err := newClient(*storageUrl, "", clientOpts())
+	if err != nil {
+		return err
+	}
+
+	targetOpts := mirrorTargetOpts(clientOpts(), storAuth(*mirrorToken, *mirrorUser, *mirrorPass, *mirrorHeaders))
+	target, err := newClient(*mirrorTo, "", targetOpts)
+	if err != nil {
+		return err
+	}
+
+	input, objects, err := readInput()
+	if err != nil {
+		return err
+	}
+
+	total := mirror(source, target, objects, *max)
+	log.WithFields(log.Fields{
+		"mirrored": total.mirrored,
+		"skipped":  total.skipped,
+		"failed":   total.failed,
+	}).Info("mirror statistics")
+
+	if input.invalid > 0 {
+		return fmt.Errorf("%d invalid lines or unreadable inputs", input.invalid)
+	}
+
+	if total.failed > 0 {
+		return fmt.Errorf("Mirror of %d objects fail", total.failed)
+	}
+
+	return nil
+}
+
+// mirrorTargetOpts returns options of client of target storage derived from options of source,
+// S3 backends and authentication of source aren't used for target (auth is authentication of target, nil means anonymous)
+func mirrorTargetOpts(opts storclient.StorClientOpts, auth storclient.Authenticator) storclient.StorClientOpts {
+	opts.S3URL = nil
+	opts.S3 = nil
+	opts.S3Auth = nil
+	opts.StorAuth = auth
+	return opts
+}
+
+type mirrorStat struct {
+	mirrored, skipped, failed int
+}
+
+// mirror copies objects missing on target from source to target
+func mirror(source, target *storclient.StorClient, objects <-chan storclient.Object, max int) mirrorStat {
+	var lock sync.Mutex
+	total := mirrorStat{}
+
+	forEachObject(objects, max, func(object storclient.Object) {
+		copied, err := mirrorObject(source, target, object)
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch {
+		case err != nil:
+			log.Errorf("Mirror of %s fail: %s", object.Sha, err)
+			total.failed++
+		case copied:
+			total.mirrored++
+		default:
+			total.skipped++
+		}
+	})
+
+	return total
+}
+
+// mirrorObject copies object from source to target via (verified) temporary file,
+// object which is already on target is skipped (false is returned)
+func mirrorObject(source, target *storclient.StorClient, object storclient.Object) (bool, error) {
+	ctx := context.Background()
+
+	found, err := target.Exists(ctx, object.Sha)
+	if err != nil {
+		return false, err
+	}
+
+	if found {
+		log.Debugf("%s is on target already", object.Sha)
+		return false, nil
+	}
+
+	reader, err := source.Get(ctx, object.Sha)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	tempfile, err := ioutil.TempFile("", fmt.Sprintf("%s_*.temp", object.Sha))
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tempfile.Close()
+		_ = os.Remove(tempfile.Name())
+	}()
+
+	if _, err := io.Copy(tempfile, reader); err != nil {
+		return false, err
+	}
+
+	if err := target.Put(ctx, object.Sha, tempfile); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
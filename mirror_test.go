@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirror(t *testing.T) {
+	sourceServer, source, _ := newTestStorage(t, map[string][]byte{helloSha: []byte("hello"), testSha1: []byte("corrupted")})
+	defer sourceServer.Close()
+
+	targetServer, target, targetStorage := newTestStorage(t, map[string][]byte{testSha2: []byte("other")})
+	defer targetServer.Close()
+
+	total := mirror(source, target, objectsOf(t, helloSha, testSha1, testSha2), 2)
+	assert.Equal(t, mirrorStat{mirrored: 1, skipped: 1, failed: 1}, total)
+
+	content, ok := targetStorage.get(helloSha)
+	assert.True(t, ok)
+	assert.Equal(t, "hello", string(content))
+
+	_, ok = targetStorage.get(testSha1)
+	assert.False(t, ok, "corrupted object isn't mirrored")
+}
+
+func TestMirrorTargetOpts(t *testing.T) {
+	source := storclient.StorClientOpts{
+		Max:      4,
+		StorAuth: storAuth("source-token", "", "", nil),
+		S3:       &storclient.S3Config{Bucket: "samples"},
+		S3Auth:   storclient.BearerAuth("s3"),
+	}
+
+	opts := mirrorTargetOpts(source, nil)
+	assert.Equal(t, 4, opts.Max)
+	assert.Nil(t, opts.StorAuth, "credentials of source don't leak to target")
+	assert.Nil(t, opts.S3)
+	assert.Nil(t, opts.S3Auth)
+
+	opts = mirrorTargetOpts(source, storAuth("target-token", "", "", nil))
+	req, err := http.NewRequest(http.MethodPut, "http://target.domain.tld/sha", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, opts.StorAuth.Authenticate(req))
+	assert.Equal(t, "Bearer target-token", req.Header.Get("Authorization"))
+}
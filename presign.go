@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	log "github.com/sirupsen/logrus"
+)
+
+func runPresign() error {
+	if *s3Bucket == "" {
+		return fmt.Errorf("required flag '--s3-bucket' not provided (set it by flag, %s or profile of config)", flagEnvar("s3-bucket"))
+	}
+
+	// stor isn't used, url of storage is optional
+	storage := &url.URL{}
+	if *storageUrl != nil {
+		storage = *storageUrl
+	}
+
+	client, err := storclient.New(*storage, "", clientOpts())
+	if err != nil {
+		return err
+	}
+
+	input, objects, err := readInput()
+	if err != nil {
+		return err
+	}
+
+	missing, failed := presign(client, objects, *max, *presignExpires, *presignVerify, os.Stdout)
+
+	if input.invalid > 0 {
+		return fmt.Errorf("%d invalid lines or unreadable inputs", input.invalid)
+	}
+
+	if missing > 0 || failed > 0 {
+		return fmt.Errorf("%d objects are missing, pre-sign of %d objects fail", missing, failed)
+	}
+
+	return nil
+}
+
+// presign writes line "SHA URL" with pre-signed url valid for expires to w for every object
+// and returns count of missing objects (only with verify) and count of objects which pre-sign fails
+func presign(client *storclient.StorClient, objects <-chan storclient.Object, max int, expires time.Duration, verify bool, w io.Writer) (missing int, failed int) {
+	var lock sync.Mutex
+
+	forEachObject(objects, max, func(object storclient.Object) {
+		u, err := client.Presign(context.Background(), object, expires, verify)
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch {
+		case errors.Is(err, storclient.ErrNotFound):
+			log.Errorf("Object %s is missing", object.Sha)
+			missing++
+		case err != nil:
+			log.Errorf("Pre-sign of %s fail: %s", object.Sha, err)
+			failed++
+		default:
+			fmt.Fprintf(w, "%s %s\n", object.Sha, u)
+		}
+	})
+
+	return missing, failed
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresign(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/samples/2c/f2/4d/"+helloSha {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}))
+	defer server.Close()
+
+	endpoint, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	client, err := storclient.New(url.URL{}, "", storclient.StorClientOpts{
+		S3: &storclient.S3Config{
+			Endpoint:    endpoint,
+			Bucket:      "samples",
+			PathStyle:   true,
+			Credentials: storclient.StaticCredentials("AKID", "SECRET", ""),
+		},
+	})
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	missing, failed := presign(client, objectsOf(t, helloSha, testSha1), 2, time.Hour, true, &out)
+	assert.Equal(t, 1, missing)
+	assert.Equal(t, 0, failed)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, 1) {
+		assert.True(t, strings.HasPrefix(lines[0], helloSha+" "+server.URL+"/samples/2c/f2/4d/"+helloSha+"?X-Amz-Algorithm="), lines[0])
+		assert.Contains(t, lines[0], "X-Amz-Expires=3600")
+	}
+
+	out.Reset()
+	missing, failed = presign(client, objectsOf(t, helloSha, testSha1), 2, time.Hour, false, &out)
+	assert.Equal(t, 0, missing)
+	assert.Equal(t, 0, failed)
+	assert.Len(t, strings.Split(strings.TrimSpace(out.String()), "\n"), 2)
+
+	out.Reset()
+	missing, failed = presign(client, objectsOf(t, helloSha), 1, 8*24*time.Hour, false, &out)
+	assert.Equal(t, 0, missing)
+	assert.Equal(t, 1, failed, "expiration is too long")
+	assert.Empty(t, out.String())
+}
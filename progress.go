@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// modes of progress reporting (see --progress)
+const (
+	progressAuto = "auto"
+	progressBar  = "bar"
+	progressLog  = "log"
+	progressNone = "none"
+)
+
+var progressModes = []string{progressAuto, progressBar, progressLog, progressNone}
+
+// refresh interval of progress bar
+const progressBarInterval = 200 * time.Millisecond
+
+// width of bar (count of characters between brackets)
+const progressBarWidth = 20
+
+// progressSource is source of progress snapshots (StorClient)
+type progressSource interface {
+	Progress() storclient.Progress
+}
+
+// progressReporter periodically reports progress of downloads,
+// as progress bar redrawn on one line of terminal or as log lines
+type progressReporter struct {
+	source    progressSource
+	bar       io.Writer
+	interval  time.Duration
+	startTime time.Time
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// progressMode resolves auto mode: bar if stderr is terminal and logs aren't in json, log otherwise
+func progressMode(mode string, json bool) string {
+	if mode != progressAuto {
+		return mode
+	}
+
+	if !json && isTerminal(os.Stderr) {
+		return progressBar
+	}
+
+	return progressLog
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// startProgress starts reporting of progress of source in mode (see --progress),
+// returns nil if mode is none
+func startProgress(source progressSource, mode string, logInterval time.Duration, startTime time.Time) *progressReporter {
+	if mode == progressNone {
+		return nil
+	}
+
+	reporter := &progressReporter{
+		source:    source,
+		interval:  logInterval,
+		startTime: startTime,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	if mode == progressBar {
+		reporter.bar = os.Stderr
+		reporter.interval = progressBarInterval
+	}
+
+	go reporter.run()
+
+	return reporter
+}
+
+func (reporter *progressReporter) run() {
+	defer close(reporter.done)
+
+	ticker := time.NewTicker(reporter.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			reporter.report()
+		case <-reporter.stop:
+			if reporter.bar != nil {
+				reporter.report()
+				fmt.Fprintln(reporter.bar)
+			}
+			return
+		}
+	}
+}
+
+func (reporter *progressReporter) report() {
+	progress := reporter.source.Progress()
+	elapsed := time.Since(reporter.startTime)
+
+	if reporter.bar != nil {
+		// \r returns to start of line and \x1b[K clears rest of previous line
+		fmt.Fprintf(reporter.bar, "\r%s\x1b[K", formatProgress(progress, elapsed))
+		return
+	}
+
+	log.WithFields(progressFields(progress, elapsed)).Info("progress")
+}
+
+// Stop stops reporting, last state of progress bar is drawn and line is finished
+func (reporter *progressReporter) Stop() {
+	if reporter == nil {
+		return
+	}
+
+	close(reporter.stop)
+	<-reporter.done
+}
+
+// formatProgress returns one line progress bar, e.g.
+//
+//	[========>           ] 450/1000 files (2 failed, 10 skipped) 1.2GB 25.3MB/s 8 active ETA 1m12s
+func formatProgress(progress storclient.Progress, elapsed time.Duration) string {
+	filled := 0
+	if progress.Queued > 0 {
+		filled = int(progress.Processed() * progressBarWidth / progress.Queued)
+	}
+
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+
+	return fmt.Sprintf("[%s] %d/%d files (%d failed, %d skipped) %s %s/s %d active ETA %s",
+		bar,
+		progress.Processed(),
+		progress.Queued,
+		progress.Failed,
+		progress.Skipped,
+		formatSize(float64(progress.Size)),
+		formatSize(rate(progress, elapsed)),
+		progress.Active,
+		formatETA(eta(progress, elapsed)),
+	)
+}
+
+// progressFields returns fields of progress log line
+func progressFields(progress storclient.Progress, elapsed time.Duration) log.Fields {
+	return log.Fields{
+		"queued":  progress.Queued,
+		"done":    progress.Done,
+		"failed":  progress.Failed,
+		"skipped": progress.Skipped,
+		"active":  progress.Active,
+		"size":    formatSize(float64(progress.Size)),
+		"rate":    formatSize(rate(progress, elapsed)) + "/s",
+		"eta":     formatETA(eta(progress, elapsed)),
+	}
+}
+
+// rate returns download rate in bytes per second
+func rate(progress storclient.Progress, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+
+	return float64(progress.Size) / elapsed.Seconds()
+}
+
+// eta estimates remaining time from average time of processed objects,
+// negative duration means unknown (nothing is processed yet)
+func eta(progress storclient.Progress, elapsed time.Duration) time.Duration {
+	processed := progress.Processed()
+	if processed == 0 {
+		return -1
+	}
+
+	return time.Duration(float64(elapsed) / float64(processed) * float64(progress.Remaining()))
+}
+
+func formatETA(eta time.Duration) string {
+	if eta < 0 {
+		return "?"
+	}
+
+	return eta.Round(time.Second).String()
+}
+
+// formatSize returns human readable size in binary units
+func formatSize(size float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%.0f%s", size, units[unit])
+	}
+
+	return fmt.Sprintf("%.1f%s", size, units[unit])
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+type testProgressSource storclient.Progress
+
+func (source testProgressSource) Progress() storclient.Progress {
+	return storclient.Progress(source)
+}
+
+func TestFormatProgress(t *testing.T) {
+	progress := storclient.Progress{Queued: 100, Done: 40, Failed: 5, Skipped: 5, Size: 50 * 1024 * 1024, Active: 4}
+
+	assert.Equal(t,
+		"[==========>         ] 50/100 files (5 failed, 5 skipped) 50.0MB 5.0MB/s 4 active ETA 10s",
+		formatProgress(progress, 10*time.Second),
+	)
+
+	assert.Equal(t,
+		"[>                   ] 0/0 files (0 failed, 0 skipped) 0B 0B/s 0 active ETA ?",
+		formatProgress(storclient.Progress{}, 0),
+	)
+
+	assert.Equal(t,
+		"[====================] 3/3 files (0 failed, 0 skipped) 3B 1B/s 0 active ETA 0s",
+		formatProgress(storclient.Progress{Queued: 3, Done: 3, Size: 3}, 3*time.Second),
+	)
+}
+
+func TestProgressFields(t *testing.T) {
+	fields := progressFields(storclient.Progress{Queued: 4, Done: 1, Size: 2048}, 2*time.Second)
+
+	assert.Equal(t, int64(4), fields["queued"])
+	assert.Equal(t, "2.0KB", fields["size"])
+	assert.Equal(t, "1.0KB/s", fields["rate"])
+	assert.Equal(t, "6s", fields["eta"])
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "1023B", formatSize(1023))
+	assert.Equal(t, "1.5KB", formatSize(1536))
+	assert.Equal(t, "2.0GB", formatSize(2*1024*1024*1024))
+}
+
+func TestProgressMode(t *testing.T) {
+	assert.Equal(t, progressNone, progressMode(progressNone, false))
+	assert.Equal(t, progressBar, progressMode(progressBar, true))
+	assert.Equal(t, progressLog, progressMode(progressAuto, true), "json logs")
+
+	assert.Nil(t, startProgress(testProgressSource{}, progressNone, time.Second, time.Now()))
+	startProgress(testProgressSource{}, progressLog, time.Millisecond, time.Now()).Stop()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"os"
+
+	"github.com/avast/stor-client/client"
+	// pure go driver of SQLite (without cgo)
+	_ "modernc.org/sqlite"
+)
+
+// defaultResolveQuery is query of mapping in SQLite database (--resolve-query)
+const defaultResolveQuery = "SELECT md5, sha1, sha256 FROM samples"
+
+// sqliteMagic is header of SQLite database file
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+// isSQLite returns true if file is SQLite database (by its header)
+func isSQLite(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	header := make([]byte, len(sqliteMagic))
+	if _, err := io.ReadFull(file, header); err != nil {
+		// shorter file can't be database
+		return false, nil
+	}
+
+	return bytes.Equal(header, sqliteMagic), nil
+}
+
+// newSQLiteResolver returns resolver with mapping read by query from SQLite database
+func newSQLiteResolver(path string, query string) (storclient.HashResolver, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return storclient.NewSQLMappingResolver(context.Background(), db, query)
+}
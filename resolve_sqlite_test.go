@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteResolver(t *testing.T) {
+	md5 := "0123456789abcdef0123456789abcdef"
+	sha1 := "0123456789abcdef0123456789abcdef01234567"
+
+	tempdir, err := ioutil.TempDir("", "mapping")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	database := filepath.Join(tempdir, "mapping.sqlite")
+	db, err := sql.Open("sqlite", database)
+	assert.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE samples (md5 TEXT, sha1 TEXT, sha256 TEXT NOT NULL)")
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO samples VALUES (?, NULL, ?), (NULL, ?, ?)", md5, testSha1, sha1, testSha2)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	csv := filepath.Join(tempdir, "mapping.csv")
+	assert.NoError(t, ioutil.WriteFile(csv, []byte("md5,sha256\n"+md5+","+testSha2+"\n"), 0644))
+
+	sqlite, err := isSQLite(database)
+	assert.NoError(t, err)
+	assert.True(t, sqlite)
+	sqlite, err = isSQLite(csv)
+	assert.NoError(t, err)
+	assert.False(t, sqlite)
+
+	resolver, err := newResolver("", database, defaultResolveQuery, 0)
+	assert.NoError(t, err)
+	sha, err := resolver.Resolve(context.Background(), md5)
+	assert.NoError(t, err)
+	assert.Equal(t, testSha1, sha.String())
+	sha, err = resolver.Resolve(context.Background(), sha1)
+	assert.NoError(t, err)
+	assert.Equal(t, testSha2, sha.String())
+
+	resolver, err = newResolver("", csv, defaultResolveQuery, 0)
+	assert.NoError(t, err)
+	sha, err = resolver.Resolve(context.Background(), md5)
+	assert.NoError(t, err)
+	assert.Equal(t, testSha2, sha.String(), "CSV mapping")
+
+	_, err = newResolver("", database, "SELECT md5, sha256 FROM missing", 0)
+	assert.Error(t, err, "missing table")
+
+	_, err = newResolver("", database, "SELECT md5 FROM samples", 0)
+	assert.Error(t, err, "missing sha256 column")
+}
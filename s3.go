@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/avast/stor-client/client"
+)
+
+// objectListing is JSON line of list command
+type objectListing struct {
+	Key          string     `json:"key"`
+	Sha256       string     `json:"sha256,omitempty"`
+	Size         int64      `json:"size"`
+	LastModified *time.Time `json:"last_modified,omitempty"`
+	ETag         string     `json:"etag,omitempty"`
+	StorageClass string     `json:"storage_class,omitempty"`
+}
+
+// flagS3Config returns native S3 backend by flags (nil without --s3-bucket)
+func flagS3Config() *storclient.S3Config {
+	if *s3Bucket == "" {
+		return nil
+	}
+
+	return &storclient.S3Config{
+		Endpoint:      *s3Endpoint,
+		Bucket:        *s3Bucket,
+		Region:        *s3Region,
+		PathStyle:     *s3PathStyle,
+		Credentials:   flagS3Credentials(),
+		RequesterPays: *s3Payer,
+		Header:        s3Header(*s3Headers),
+	}
+}
+
+func runList() error {
+	config := flagS3Config()
+	if config == nil {
+		return fmt.Errorf("required flag '--s3-bucket' not provided (set it by flag, %s or profile of config)", flagEnvar("s3-bucket"))
+	}
+
+	s3, err := storclient.NewS3Client(*config, &http.Client{Timeout: *timeout})
+	if err != nil {
+		return err
+	}
+
+	return list(context.Background(), s3, *listPrefix, storclient.Algorithm(*algorithm), os.Stdout)
+}
+
+// list writes objects of bucket with prefix as JSON lines to w,
+// sha is parsed from last part of key (by S3 template)
+func list(ctx context.Context, s3 *storclient.S3Client, prefix string, algorithm storclient.Algorithm, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+
+	return s3.ListObjects(ctx, prefix, func(info storclient.S3ObjectInfo) error {
+		entry := objectListing{Key: info.Key, Size: info.Size, ETag: info.ETag, StorageClass: info.StorageClass}
+		if sha, err := algorithm.ParseHash(path.Base(info.Key)); err == nil {
+			entry.Sha256 = sha.String()
+		}
+		if !info.LastModified.IsZero() {
+			entry.LastModified = &info.LastModified
+		}
+
+		return encoder.Encode(entry)
+	})
+}
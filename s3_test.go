@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/samples/" || r.URL.Query().Get("prefix") != "2c/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fmt.Fprintf(w, `<ListBucketResult><IsTruncated>false</IsTruncated>
+<Contents><Key>2c/f2/4d/%s</Key><LastModified>2018-06-01T12:00:00.000Z</LastModified><ETag>"etag"</ETag><Size>5</Size><StorageClass>STANDARD</StorageClass></Contents>
+<Contents><Key>2c/readme.txt</Key><Size>10</Size></Contents>
+</ListBucketResult>`, helloSha)
+	}))
+	defer server.Close()
+
+	endpoint, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	s3, err := storclient.NewS3Client(storclient.S3Config{Endpoint: endpoint, Bucket: "samples", PathStyle: true}, nil)
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.NoError(t, list(context.Background(), s3, "2c/", storclient.AlgorithmSHA256, &out))
+
+	decoder := json.NewDecoder(&out)
+	var entries []objectListing
+	for decoder.More() {
+		var entry objectListing
+		assert.NoError(t, decoder.Decode(&entry))
+		entries = append(entries, entry)
+	}
+
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, helloSha, entries[0].Sha256)
+		assert.Equal(t, int64(5), entries[0].Size)
+		assert.Equal(t, "STANDARD", entries[0].StorageClass)
+		assert.NotNil(t, entries[0].LastModified)
+		assert.Equal(t, objectListing{Key: "2c/readme.txt", Size: 10}, entries[1])
+	}
+
+	assert.Error(t, list(context.Background(), s3, "missing/", storclient.AlgorithmSHA256, &out))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// objectStat is JSON line of stat command
+type objectStat struct {
+	Sha256       string     `json:"sha256"`
+	Size         int64      `json:"size"`
+	LastModified *time.Time `json:"last_modified,omitempty"`
+	URL          string     `json:"url"`
+}
+
+func runStat() error {
+	client, err := newClient(*storageUrl, "", clientOpts())
+	if err != nil {
+		return err
+	}
+
+	return stat(client, storclient.Algorithm(*algorithm), *statShas, os.Stdout)
+}
+
+// stat writes metadata of objects as JSON lines to w, missing object is logged
+func stat(client *storclient.StorClient, algorithm storclient.Algorithm, shas []string, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	failed := 0
+
+	for _, shaHexStr := range shas {
+		sha, err := algorithm.ParseHash(shaHexStr)
+		if err != nil {
+			log.Errorf("Invalid %s %q: %s", algorithm, shaHexStr, err)
+			failed++
+			continue
+		}
+
+		info, err := client.Stat(context.Background(), sha)
+		if err != nil {
+			log.Errorf("Stat of %s fail: %s", sha, err)
+			failed++
+			continue
+		}
+
+		entry := objectStat{Sha256: info.Sha.String(), Size: info.Size, URL: info.URL}
+		if !info.LastModified.IsZero() {
+			entry.LastModified = &info.LastModified
+		}
+
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Stat of %d objects fail", failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStat(t *testing.T) {
+	server, client, _ := newTestStorage(t, map[string][]byte{helloSha: []byte("hello")})
+	defer server.Close()
+
+	var out bytes.Buffer
+	assert.NoError(t, stat(client, storclient.AlgorithmSHA256, []string{helloSha}, &out))
+
+	var entry objectStat
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, helloSha, entry.Sha256)
+	assert.Equal(t, int64(5), entry.Size)
+	assert.Equal(t, server.URL+"/"+helloSha, entry.URL)
+
+	assert.Error(t, stat(client, storclient.AlgorithmSHA256, []string{testSha1, "nosha"}, &out))
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/avast/stor-client/client"
+)
+
+// exit codes of download
+//
+// if more reasons occur, code with highest priority is used:
+// hash mismatch, permanent failure, transient failure, not found, invalid input, failed --exec command
+const (
+	// success
+	exitOK = 0
+	// fatal error (e.g. invalid arguments, unwritable output)
+	exitFatal = 1
+	// some objects aren't on storage (retry doesn't help)
+	exitNotFound = 2
+	// some downloads fail because of network or server errors of storage (retry can help)
+	exitTransient = 3
+	// content of some objects doesn't match its hash
+	exitHashMismatch = 4
+	// some input lines are invalid or some inputs are unreadable
+	exitInvalidInput = 5
+	// some --exec commands fail
+	exitExecFail = 6
+	// some downloads fail permanently, e.g. access denied, invalid template or unwritable destination (retry doesn't help)
+	exitPermanent = 7
+)
+
+// exitCodeError is error with exit code of process
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (err exitCodeError) Error() string {
+	return err.err.Error()
+}
+
+// runSummary is summary of download written by --summary-json
+type runSummary struct {
+	storclient.Summary
+	InvalidInputs int `json:"invalid_inputs"`
+	ExecFailed    int `json:"exec_failed"`
+	ExitCode      int `json:"exit_code"`
+}
+
+// transientFailures returns count of failures because of network or server errors of storage
+func transientFailures(total storclient.TotalStat) int {
+	return total.NetworkError + total.ServerError
+}
+
+// permanentFailures returns count of failures of other reasons than not found, hash mismatch or transient one
+func permanentFailures(total storclient.TotalStat) int {
+	return total.Fail - total.NotFound - total.HashMismatch - transientFailures(total)
+}
+
+// exitCode returns exit code of download by total stats, count of invalid inputs and failed --exec commands
+func exitCode(total storclient.TotalStat, invalid int, execFailed int) int {
+	switch {
+	case total.HashMismatch > 0:
+		return exitHashMismatch
+	case permanentFailures(total) > 0:
+		return exitPermanent
+	case transientFailures(total) > 0:
+		return exitTransient
+	case total.NotFound > 0:
+		return exitNotFound
+	case invalid > 0:
+		return exitInvalidInput
+	case execFailed > 0:
+		return exitExecFail
+	default:
+		return exitOK
+	}
+}
+
+// downloadResult returns nil or exitCodeError with description of failures
+func downloadResult(total storclient.TotalStat, invalid int, execFailed int) error {
+	code := exitCode(total, invalid, execFailed)
+	switch code {
+	case exitOK:
+		return nil
+	case exitInvalidInput:
+		return exitCodeError{code: code, err: fmt.Errorf("%d invalid lines or unreadable inputs", invalid)}
+	case exitExecFail:
+		return exitCodeError{code: code, err: fmt.Errorf("%d --exec commands fail", execFailed)}
+	}
+
+	return exitCodeError{code: code, err: fmt.Errorf(
+		"Download of some files fail (%d not found, %d hash mismatch, %d transient, %d permanent; %d invalid inputs)",
+		total.NotFound, total.HashMismatch, transientFailures(total), permanentFailures(total), invalid,
+	)}
+}
+
+// writeSummary writes summary of download as JSON to path ('-' means stdout)
+func writeSummary(path string, total storclient.TotalStat, elapsed time.Duration, invalid int, execFailed int) (err error) {
+	out := os.Stdout
+	if path != stdinInput {
+		if out, err = os.Create(path); err != nil {
+			return err
+		}
+
+		defer func() {
+			if errClose := out.Close(); errClose != nil && err == nil {
+				err = errClose
+			}
+		}()
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(runSummary{
+		Summary:       total.Summary(elapsed),
+		InvalidInputs: invalid,
+		ExecFailed:    execFailed,
+		ExitCode:      exitCode(total, invalid, execFailed),
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, exitOK, exitCode(storclient.TotalStat{Count: 2}, 0, 0))
+	assert.Equal(t, exitInvalidInput, exitCode(storclient.TotalStat{Count: 2}, 1, 0))
+	assert.Equal(t, exitNotFound, exitCode(storclient.TotalStat{Fail: 1, NotFound: 1}, 1, 0))
+	assert.Equal(t, exitTransient, exitCode(storclient.TotalStat{Fail: 2, NotFound: 1, NetworkError: 1}, 0, 0))
+	assert.Equal(t, exitTransient, exitCode(storclient.TotalStat{Fail: 1, ServerError: 1}, 0, 0))
+	assert.Equal(t, exitPermanent, exitCode(storclient.TotalStat{Fail: 1}, 0, 0), "other failures are permanent")
+	assert.Equal(t, exitPermanent, exitCode(storclient.TotalStat{Fail: 3, NotFound: 1, NetworkError: 1}, 0, 0))
+	assert.Equal(t, exitExecFail, exitCode(storclient.TotalStat{Count: 2}, 0, 1))
+	assert.Equal(t, exitInvalidInput, exitCode(storclient.TotalStat{Count: 2}, 1, 1))
+	assert.Equal(t, exitHashMismatch, exitCode(storclient.TotalStat{Fail: 3, NotFound: 1, HashMismatch: 1, NetworkError: 1}, 1, 0))
+
+	assert.NoError(t, downloadResult(storclient.TotalStat{}, 0, 0))
+
+	err := downloadResult(storclient.TotalStat{Fail: 1, NotFound: 1}, 0, 0)
+	if assert.IsType(t, exitCodeError{}, err) {
+		assert.Equal(t, exitNotFound, err.(exitCodeError).code)
+		assert.Contains(t, err.Error(), "1 not found")
+	}
+}
+
+func TestExitCodeOfDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + helloSha:
+			fmt.Fprint(w, "hello")
+		case "/" + testSha1:
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	tempdir, err := ioutil.TempDir("", "exitcode")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	// regular file can't be parent of downloaded files
+	file := filepath.Join(tempdir, "file")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("file"), 0644))
+
+	download := func(downloadDir string, shas ...string) int {
+		client, err := storclient.New(*serverURL, downloadDir, storclient.StorClientOpts{RetryAttempts: 2, RetryDelay: time.Millisecond})
+		assert.NoError(t, err)
+
+		client.Start()
+		for object := range objectsOf(t, shas...) {
+			client.Download(object.Sha)
+		}
+		total := client.Wait()
+		assert.NoError(t, client.Close())
+
+		return exitCode(total, 0, 0)
+	}
+
+	assert.Equal(t, exitOK, download(tempdir, helloSha))
+	assert.Equal(t, exitTransient, download(tempdir, testSha2), "503")
+	assert.Equal(t, exitPermanent, download(tempdir, testSha1, testSha2), "403")
+	assert.Equal(t, exitPermanent, download(file, helloSha), "unwritable destination")
+}
+
+func TestWriteSummary(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "summary")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	path := filepath.Join(tempdir, "summary.json")
+	total := storclient.TotalStat{Size: 10, Count: 1, Fail: 1, NotFound: 1, Retries: 2}
+	assert.NoError(t, writeSummary(path, total, 2*time.Second, 3, 1))
+
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	var summary map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &summary))
+	assert.Equal(t, 1.0, summary["downloaded"])
+	assert.Equal(t, 1.0, summary["not_found"])
+	assert.Equal(t, 2.0, summary["retries"])
+	assert.Equal(t, 5.0, summary["rate_bytes_per_second"])
+	assert.Equal(t, 3.0, summary["invalid_inputs"])
+	assert.Equal(t, 1.0, summary["exec_failed"])
+	assert.Equal(t, float64(exitNotFound), summary["exit_code"])
+
+	assert.Error(t, writeSummary(filepath.Join(tempdir, "missing", "summary.json"), total, time.Second, 0, 0))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+)
+
+// unwrap restores original content of wrapped file to destination ('-' means stdout)
+//
+// expected hash (of algorithm) is shaHexStr or (if is empty) is parsed from file name
+func unwrap(file, destination, shaHexStr string, key []byte, algorithm storclient.Algorithm) (err error) {
+	if shaHexStr == "" {
+		shas := parseShas(filepath.Base(file), algorithm)
+		if len(shas) == 0 {
+			return fmt.Errorf("%s can't be parsed from file name %s, use --sha", algorithm, file)
+		}
+		shaHexStr = shas[0]
+	}
+
+	sha, err := algorithm.ParseHash(shaHexStr)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := in.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+	}()
+
+	if destination == "-" {
+		return storclient.Unwrap(in, os.Stdout, key, sha, algorithm)
+	}
+
+	out, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+
+	if err = unwrapTo(in, out, key, sha, algorithm); err != nil {
+		_ = os.Remove(destination)
+	}
+
+	return err
+}
+
+func unwrapTo(in io.Reader, out *os.File, key []byte, sha hashutil.Hash, algorithm storclient.Algorithm) error {
+	if err := storclient.Unwrap(in, out, key, sha, algorithm); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnwrap(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "unwrap")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	// sha256 of "hello"
+	sha := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	key := []byte("secret")
+
+	output, err := storclient.NewSafeDirOutput(tempdir, storclient.SafeOpts{Wrapping: storclient.WrapAES, Key: key})
+	assert.NoError(t, err)
+
+	tempfile, err := pathutil.NewTempFile(pathutil.TempOpt{Dir: tempdir})
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(tempfile.Canonpath(), []byte("hello"), 0644))
+	assert.NoError(t, output.Store(sha+".dat", tempfile, time.Now()))
+
+	destination := filepath.Join(tempdir, "restored")
+	assert.NoError(t, unwrap(filepath.Join(tempdir, sha+".dat"), destination, "", key, storclient.AlgorithmSHA256))
+
+	restored, err := ioutil.ReadFile(destination)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(restored))
+
+	badDestination := filepath.Join(tempdir, "bad")
+	assert.Error(t, unwrap(filepath.Join(tempdir, sha+".dat"), badDestination, "", []byte("bad key"), storclient.AlgorithmSHA256))
+	_, err = os.Stat(badDestination)
+	assert.True(t, os.IsNotExist(err), "destination is removed if sha doesn't match")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/avast/stor-client/client"
+	log "github.com/sirupsen/logrus"
+)
+
+func runUpload() error {
+	client, err := newClient(*storageUrl, "", clientOpts())
+	if err != nil {
+		return err
+	}
+
+	return upload(client, *uploadFiles, os.Stdout)
+}
+
+// upload uploads files to storage and writes line "SHA file" to w for every uploaded file
+func upload(client *storclient.StorClient, files []string, w io.Writer) error {
+	failed := 0
+	for _, file := range files {
+		sha, err := client.Upload(context.Background(), file)
+		if err != nil {
+			log.Errorf("Upload of %s fail: %s", file, err)
+			failed++
+			continue
+		}
+
+		fmt.Fprintf(w, "%s %s\n", sha, file)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Upload of %d files fail", failed)
+	}
+
+	return nil
+}
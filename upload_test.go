@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpload(t *testing.T) {
+	server, client, storage := newTestStorage(t, map[string][]byte{})
+	defer server.Close()
+
+	tempdir, err := ioutil.TempDir("", "upload")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	file := filepath.Join(tempdir, "hello.txt")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("hello"), 0644))
+
+	var out bytes.Buffer
+	assert.NoError(t, upload(client, []string{file}, &out))
+	assert.Equal(t, helloSha+" "+file+"\n", out.String())
+
+	content, ok := storage.get(helloSha)
+	assert.True(t, ok)
+	assert.Equal(t, "hello", string(content))
+
+	assert.Error(t, upload(client, []string{filepath.Join(tempdir, "missing")}, &out))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/avast/stor-client/client"
+	log "github.com/sirupsen/logrus"
+)
+
+func runVerify() error {
+	mismatched, err := verify(*verifyDir, storclient.Algorithm(*algorithm), os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if mismatched > 0 {
+		return fmt.Errorf("%d files are corrupted", mismatched)
+	}
+
+	return nil
+}
+
+// verify computes hash (algorithm) of every file in dir (recursively) with SHA in name
+// and writes line "path: expected SHA, got SHA" to w for every mismatched file
+//
+// files without SHA in name and temporary files are skipped,
+// files stored wrapped (--wrap) or zipped (--zip) can't be verified this way
+func verify(dir string, algorithm storclient.Algorithm, w io.Writer) (mismatched int, err error) {
+	verified := 0
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() || isTempFile(info.Name()) {
+			return nil
+		}
+
+		shas := parseShas(info.Name(), algorithm)
+		if len(shas) == 0 {
+			log.Debugf("Skip %s without %s in name", path, algorithm)
+			return nil
+		}
+
+		got, err := hashFile(path, algorithm)
+		if err != nil {
+			return err
+		}
+
+		verified++
+		if got != strings.ToLower(shas[0]) {
+			mismatched++
+			fmt.Fprintf(w, "%s: expected %s, got %s\n", path, strings.ToLower(shas[0]), got)
+		}
+
+		return nil
+	})
+
+	log.Infof("Verified %d files, %d mismatched", verified, mismatched)
+
+	return mismatched, err
+}
+
+func hashFile(path string, algorithm storclient.Algorithm) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := algorithm.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
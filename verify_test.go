@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "verify")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempdir, "2c"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, "2c", helloSha+".dat"), []byte("hello"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, testSha1), []byte("corrupted"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, testSha2+"_123.temp"), []byte("partial"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, "README"), []byte("readme"), 0644))
+
+	var out bytes.Buffer
+	mismatched, err := verify(tempdir, storclient.AlgorithmSHA256, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mismatched)
+	assert.Contains(t, out.String(), filepath.Join(tempdir, testSha1)+": expected "+testSha1)
+}